@@ -15,40 +15,90 @@
 package main
 
 import (
-	"context"
+	"fmt"
 	"os"
 	"runtime"
 
+	"github.com/jmckind/jira-operator/pkg/apis"
+	"github.com/jmckind/jira-operator/pkg/leader"
+	"github.com/jmckind/jira-operator/pkg/metrics"
 	stub "github.com/jmckind/jira-operator/pkg/stub"
+	"github.com/jmckind/jira-operator/pkg/webhook"
 	"github.com/jmckind/jira-operator/version"
-	sdk "github.com/operator-framework/operator-sdk/pkg/sdk"
-	k8sutil "github.com/operator-framework/operator-sdk/pkg/util/k8sutil"
-	sdkVersion "github.com/operator-framework/operator-sdk/version"
 
 	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
 )
 
+// watchNamespaceEnvVar is the environment variable with the namespace watched
+// by the operator. An empty namespace watches all namespaces.
+const watchNamespaceEnvVar = "WATCH_NAMESPACE"
+
 func main() {
 	configureLogging()
 	printVersion()
 
-	resource := "app.redhat.com/v1alpha1"
-	kind := "Jira"
-	namespace, err := k8sutil.GetWatchNamespace()
+	namespace, err := getWatchNamespace()
 	if err != nil {
 		log.Fatalf("Failed to get watch namespace: %v", err)
 	}
-	resyncPeriod := 5
-	log.Infof("Watching %s, %s, %s, %d", resource, kind, namespace, resyncPeriod)
-	sdk.Watch(resource, kind, namespace, resyncPeriod)
-	sdk.Handle(stub.NewJiraHandler())
-	sdk.Run(context.TODO())
+	cfg, err := config.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get kubernetes config: %v", err)
+	}
+	mgr, err := manager.New(cfg, manager.Options{Namespace: namespace})
+	if err != nil {
+		log.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Fatalf("Failed to register types: %v", err)
+	}
+	if err := stub.Add(mgr); err != nil {
+		log.Fatalf("Failed to create controllers: %v", err)
+	}
+	log.Infof("Watching Jira and JiraRestore resources in namespace %q", namespace)
+
+	go func() {
+		log.Fatalf("Metrics server failed: %v", metrics.Serve())
+	}()
+	if webhook.Enabled() {
+		go func() {
+			log.Fatalf("Webhook server failed: %v", webhook.Serve())
+		}()
+	} else {
+		log.Infof("No webhook certificate found in %s, admission webhooks are disabled", webhook.CertDirEnvVar)
+	}
+
+	start := func(stop <-chan struct{}) {
+		if err := mgr.Start(stop); err != nil {
+			log.Fatalf("Manager exited: %v", err)
+		}
+	}
+	if !leader.Enabled() {
+		start(signals.SetupSignalHandler())
+		return
+	}
+	// Only the leader runs the controllers, the admission webhooks and the
+	// metrics are served by all replicas.
+	if err := leader.Run(cfg, mgr.GetRecorder("jira-operator"), start); err != nil {
+		log.Fatalf("Leader election failed: %v", err)
+	}
+}
+
+// getWatchNamespace returns the namespace watched by the operator.
+func getWatchNamespace() (string, error) {
+	ns, found := os.LookupEnv(watchNamespaceEnvVar)
+	if !found {
+		return "", fmt.Errorf("%s must be set", watchNamespaceEnvVar)
+	}
+	return ns, nil
 }
 
 func printVersion() {
 	log.Infof("Go Version: %s", runtime.Version())
 	log.Infof("Go OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH)
-	log.Infof("operator-sdk Version: %v", sdkVersion.Version)
 	log.Infof("jira-operator Version: %v", version.Version)
 }
 
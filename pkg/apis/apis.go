@@ -0,0 +1,41 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apis contains the API types of the operator and of the foreign
+// resources it manages.
+package apis
+
+import (
+	jirav1alpha1 "github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	jirav1beta1 "github.com/jmckind/jira-operator/pkg/apis/jira/v1beta1"
+	monitoringv1 "github.com/jmckind/jira-operator/pkg/apis/monitoring/v1"
+	routev1 "github.com/jmckind/jira-operator/pkg/apis/route/v1"
+	snapshotv1alpha1 "github.com/jmckind/jira-operator/pkg/apis/snapshot/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AddToSchemes adds all API types of the operator to a Scheme.
+var AddToSchemes = runtime.SchemeBuilder{
+	jirav1alpha1.AddToScheme,
+	jirav1beta1.AddToScheme,
+	monitoringv1.AddToScheme,
+	routev1.AddToScheme,
+	snapshotv1alpha1.AddToScheme,
+}
+
+// AddToScheme adds all API types of the operator to the Scheme.
+func AddToScheme(s *runtime.Scheme) error {
+	return AddToSchemes.AddToScheme(s)
+}
@@ -15,8 +15,6 @@
 package v1alpha1
 
 import (
-	sdkK8sutil "github.com/operator-framework/operator-sdk/pkg/util/k8sutil"
-
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -34,15 +32,13 @@ var (
 	SchemeGroupVersion = schema.GroupVersion{Group: groupName, Version: version}
 )
 
-func init() {
-	sdkK8sutil.AddToSDKScheme(AddToScheme)
-}
-
 // addKnownTypes adds the set of types defined in this package to the supplied scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Jira{},
 		&JiraList{},
+		&JiraRestore{},
+		&JiraRestoreList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
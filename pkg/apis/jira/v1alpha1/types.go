@@ -15,6 +15,8 @@
 package v1alpha1
 
 import (
+	"strings"
+
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -26,6 +28,18 @@ const (
 	DefaultBaseImageVersion = "7.10.2"
 	// DefaultDataMountPath is the default filesystem path for JIRA Home.
 	DefaultDataMountPath = "/var/atlassian/jira"
+	// DefaultSharedHomeMountPath is the default filesystem path for the
+	// shared home of a JIRA Data Center cluster.
+	DefaultSharedHomeMountPath = "/var/atlassian/jira-shared"
+	// DefaultEhcacheListenerPort is the default port of the Ehcache RMI
+	// listener of a cluster node.
+	DefaultEhcacheListenerPort = 40001
+	// DefaultEhcacheObjectPort is the default port of the Ehcache remote
+	// objects of a cluster node.
+	DefaultEhcacheObjectPort = 40011
+	// DefaultDrainSeconds is the default time a cluster node is removed from
+	// the Service before it is restarted.
+	DefaultDrainSeconds = 30
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -47,19 +61,20 @@ type Jira struct {
 	Status            JiraStatus `json:"status,omitempty"`
 }
 
-// JiraPodPolicy defines the policy for pods owned by rethinkdb operator.
+// JiraPodPolicy defines the policy for the JIRA pods.
 type JiraPodPolicy struct {
 	// Resources is the resource requirements for the jira container.
 	Resources v1.ResourceRequirements `json:"resources,omitempty"`
 
 	// PersistentVolumeClaimSpec is the spec to describe PVC for the jira container
 	// This field is optional. If no PVC spec, jira container will use emptyDir as volume
+	// Deprecated: use storage.localHome instead.
 	PersistentVolumeClaimSpec *v1.PersistentVolumeClaimSpec `json:"persistentVolumeClaimSpec,omitempty"`
 }
 
 // JiraSpec resource
 type JiraSpec struct {
-	// BaseImage image to use for a RethinkDB deployment.
+	// BaseImage is the image to use for the JIRA pods.
 	BaseImage string `json:"base_image"`
 
 	// BaseImageVersion is the version of base image to use.
@@ -71,15 +86,591 @@ type JiraSpec struct {
 	// ConfigMapName is the name of ConfigMap to use or create.
 	ConfigMapName string `json:"configMapName"`
 
-	// SecretName is the name of Secret to use or create.
+	// SecretName is the name of the Secret to use. The Secret holds the
+	// database credentials (username, password) and optionally the JIRA
+	// license (license) and the credentials of a JIRA administrator
+	// (admin-username, admin-password), which are mounted as files into the
+	// JIRA container.
 	SecretName string `json:"secretName"`
 
-	// Pod defines the policy for pods owned by rethinkdb operator.
-	// This field cannot be updated once the CR is created.
+	// Replicas is the number of JIRA nodes. More than one node requires JIRA
+	// Data Center, an external database and a shared home. Defaults to 1.
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Cluster defines the JIRA Data Center cluster settings. It is only used
+	// if a shared home is defined.
+	Cluster *JiraClusterSpec `json:"cluster,omitempty"`
+
+	// Pod defines the policy for the JIRA pods. Changes to the resources are
+	// rolled out to the pods, the volume claim of JIRA Home cannot be changed
+	// once the CR is created.
 	Pod *JiraPodPolicy `json:"pod,omitempty"`
+
+	// Upgrade defines how changes to BaseImageVersion are rolled out.
+	Upgrade *JiraUpgradePolicy `json:"upgrade,omitempty"`
+
+	// Database defines the database used by JIRA. The dbconfig.xml in the
+	// ConfigMap is rendered from this spec. If no database is defined, the
+	// embedded H2 database is used.
+	Database *JiraDatabaseSpec `json:"database,omitempty"`
+
+	// Expose defines how JIRA is exposed outside of the cluster.
+	Expose *JiraExposeSpec `json:"expose,omitempty"`
+
+	// Probes defines the readiness and liveness probes of the JIRA container.
+	Probes *JiraProbeSpec `json:"probes,omitempty"`
+
+	// Storage defines what happens to the data volumes of JIRA.
+	Storage *JiraStorageSpec `json:"storage,omitempty"`
+
+	// Backup defines scheduled backups of JIRA Home and the database to
+	// S3-compatible object storage.
+	Backup *JiraBackupSpec `json:"backup,omitempty"`
+
+	// Monitoring defines the export of JIRA application metrics to
+	// Prometheus.
+	Monitoring *JiraMonitoringSpec `json:"monitoring,omitempty"`
+}
+
+// StorageReclaimPolicy defines what happens to the JIRA Home PVCs when the
+// Jira resource is deleted.
+type StorageReclaimPolicy string
+
+const (
+	// StorageReclaimRetain keeps the PVCs.
+	StorageReclaimRetain StorageReclaimPolicy = "Retain"
+	// StorageReclaimDelete deletes the PVCs.
+	StorageReclaimDelete StorageReclaimPolicy = "Delete"
+	// StorageReclaimSnapshot takes a VolumeSnapshot of each PVC and deletes
+	// the PVCs once the snapshots are ready. If a snapshot fails the deletion
+	// is held back with the ReclaimFailed condition until the policy is
+	// changed to Retain or Delete.
+	StorageReclaimSnapshot StorageReclaimPolicy = "Snapshot"
+)
+
+// JiraStorageSpec defines the handling of the JIRA data volumes.
+type JiraStorageSpec struct {
+	// ReclaimPolicy defines what happens to the PVCs when the Jira resource
+	// is deleted. One of Retain, Delete or Snapshot. Defaults to Retain.
+	ReclaimPolicy StorageReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// SnapshotClassName is the VolumeSnapshotClass used by the Snapshot
+	// reclaim policy and snapshot backups. The default class is used if
+	// empty.
+	SnapshotClassName string `json:"snapshotClassName,omitempty"`
+
+	// DataSource is the VolumeSnapshot a new JIRA Home PVC is created from.
+	// It is ignored once the PVC exists.
+	DataSource *JiraDataSource `json:"dataSource,omitempty"`
+
+	// LocalHome is the JIRA Home of each node, which holds the indexes,
+	// caches and logs. It takes precedence over the PVC spec of the pod
+	// policy. Without a PVC spec it is an emptyDir.
+	LocalHome *JiraVolumeSpec `json:"localHome,omitempty"`
+
+	// SharedHome is the home directory shared by the nodes of a JIRA Data
+	// Center cluster. Defining a shared home enables clustering.
+	SharedHome *JiraVolumeSpec `json:"sharedHome,omitempty"`
+}
+
+// JiraVolumeSpec defines a volume of JIRA. At most one of
+// PersistentVolumeClaimSpec and EmptyDir may be set, an emptyDir is used if
+// neither is set.
+type JiraVolumeSpec struct {
+	// PersistentVolumeClaimSpec is the spec of the PVC of the volume. The
+	// shared home of a cluster with more than one node requires the
+	// ReadWriteMany access mode.
+	PersistentVolumeClaimSpec *v1.PersistentVolumeClaimSpec `json:"persistentVolumeClaimSpec,omitempty"`
+
+	// EmptyDir is the emptyDir of the volume, e.g. a memory backed volume
+	// for the indexes of the local home.
+	EmptyDir *v1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// MountPath is the path the volume is mounted at. The mount path of the
+	// local home takes precedence over dataMountPath.
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// JiraClusterSpec defines the JIRA Data Center cluster settings.
+type JiraClusterSpec struct {
+	// EhcacheListenerPort is the port of the Ehcache RMI listener.
+	EhcacheListenerPort int32 `json:"ehcacheListenerPort,omitempty"`
+
+	// EhcacheObjectPort is the port of the Ehcache remote objects.
+	EhcacheObjectPort int32 `json:"ehcacheObjectPort,omitempty"`
+
+	// DrainSeconds is the time a node is removed from the Service before it
+	// is restarted during a rolling restart.
+	DrainSeconds int32 `json:"drainSeconds,omitempty"`
+}
+
+// SetDefaults sets the default values of the cluster spec and returns true if
+// the spec was changed.
+func (c *JiraClusterSpec) SetDefaults() bool {
+	changed := false
+	if c.EhcacheListenerPort == 0 {
+		c.EhcacheListenerPort = DefaultEhcacheListenerPort
+		changed = true
+	}
+	if c.EhcacheObjectPort == 0 {
+		c.EhcacheObjectPort = DefaultEhcacheObjectPort
+		changed = true
+	}
+	if c.DrainSeconds == 0 {
+		c.DrainSeconds = DefaultDrainSeconds
+		changed = true
+	}
+	return changed
+}
+
+// JiraDataSource defines the source of a new JIRA Home PVC.
+type JiraDataSource struct {
+	// VolumeSnapshotName is the name of a VolumeSnapshot in the namespace of
+	// the Jira resource.
+	VolumeSnapshotName string `json:"volumeSnapshotName"`
+}
+
+const (
+	// DefaultProbeStartupSeconds is the default time JIRA is given to start.
+	DefaultProbeStartupSeconds = 600
+	// DefaultProbePeriodSeconds is the default interval between probes.
+	DefaultProbePeriodSeconds = 10
+	// DefaultProbeTimeoutSeconds is the default timeout of a probe.
+	DefaultProbeTimeoutSeconds = 5
+	// DefaultProbeReadinessFailureThreshold is the default number of failed
+	// readiness probes before JIRA is removed from the Service.
+	DefaultProbeReadinessFailureThreshold = 3
+	// DefaultProbeLivenessFailureThreshold is the default number of failed
+	// liveness probes before JIRA is restarted.
+	DefaultProbeLivenessFailureThreshold = 6
+)
+
+// JiraProbeSpec defines the probes against the JIRA status endpoint.
+type JiraProbeSpec struct {
+	// StartupSeconds is the time JIRA is given to start before the liveness
+	// probe is started.
+	StartupSeconds int32 `json:"startupSeconds,omitempty"`
+
+	// PeriodSeconds is the interval between probes.
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// TimeoutSeconds is the timeout of a single probe.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// ReadinessFailureThreshold is the number of failed probes before JIRA is
+	// removed from the Service.
+	ReadinessFailureThreshold int32 `json:"readinessFailureThreshold,omitempty"`
+
+	// LivenessFailureThreshold is the number of failed probes before JIRA is
+	// restarted.
+	LivenessFailureThreshold int32 `json:"livenessFailureThreshold,omitempty"`
+}
+
+// SetDefaults sets the default values for the probe spec and returns true if
+// the spec was changed.
+func (p *JiraProbeSpec) SetDefaults() bool {
+	changed := false
+	if p.StartupSeconds == 0 {
+		p.StartupSeconds = DefaultProbeStartupSeconds
+		changed = true
+	}
+	if p.PeriodSeconds == 0 {
+		p.PeriodSeconds = DefaultProbePeriodSeconds
+		changed = true
+	}
+	if p.TimeoutSeconds == 0 {
+		p.TimeoutSeconds = DefaultProbeTimeoutSeconds
+		changed = true
+	}
+	if p.ReadinessFailureThreshold == 0 {
+		p.ReadinessFailureThreshold = DefaultProbeReadinessFailureThreshold
+		changed = true
+	}
+	if p.LivenessFailureThreshold == 0 {
+		p.LivenessFailureThreshold = DefaultProbeLivenessFailureThreshold
+		changed = true
+	}
+	return changed
+}
+
+// JiraExposeSpec defines how JIRA is exposed.
+type JiraExposeSpec struct {
+	// ServiceType is the type of the JIRA Service, one of ClusterIP, NodePort
+	// or LoadBalancer. Defaults to NodePort.
+	ServiceType v1.ServiceType `json:"serviceType,omitempty"`
+
+	// Ingress defines an Ingress managed by the operator.
+	Ingress *JiraIngressSpec `json:"ingress,omitempty"`
+
+	// Route defines an OpenShift Route managed by the operator.
+	Route *JiraRouteSpec `json:"route,omitempty"`
+
+	// ContextPath is the path JIRA is served from, e.g. /jira. Defaults to
+	// the root path.
+	ContextPath string `json:"contextPath,omitempty"`
+
+	// Proxy overrides the reverse proxy settings derived from the Ingress or
+	// Route, e.g. when JIRA sits behind an external load balancer.
+	Proxy *JiraProxySpec `json:"proxy,omitempty"`
+}
+
+// JiraProxySpec defines the reverse proxy settings of the JIRA connector.
+type JiraProxySpec struct {
+	// Name is the host name clients use to reach JIRA.
+	Name string `json:"name,omitempty"`
+
+	// Port is the port clients use to reach JIRA.
+	Port int32 `json:"port,omitempty"`
+
+	// Scheme is the scheme clients use to reach JIRA, http or https.
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// JiraIngressSpec defines the Ingress for JIRA.
+type JiraIngressSpec struct {
+	// Host is the fully qualified domain name of JIRA.
+	Host string `json:"host"`
+
+	// Path is the path JIRA is served from. Defaults to the context path or /.
+	Path string `json:"path,omitempty"`
+
+	// IngressClass is the value of the kubernetes.io/ingress.class annotation.
+	IngressClass string `json:"ingressClass,omitempty"`
+
+	// Annotations are additional annotations for the Ingress.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// TLSSecretName is the name of the Secret with the TLS certificate. TLS is
+	// enabled if this field is set.
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// JiraRouteSpec defines the OpenShift Route for JIRA.
+type JiraRouteSpec struct {
+	// Host is the fully qualified domain name of JIRA. If empty, the router
+	// generates a host name.
+	Host string `json:"host,omitempty"`
+
+	// Path is the path JIRA is served from.
+	Path string `json:"path,omitempty"`
+
+	// TLSTermination is the TLS termination type, one of edge, passthrough
+	// or reencrypt. TLS is enabled if this field is set.
+	TLSTermination string `json:"tlsTermination,omitempty"`
+}
+
+// DatabaseType is the type of database used by JIRA.
+type DatabaseType string
+
+const (
+	// DatabaseTypeH2 is the embedded H2 database.
+	DatabaseTypeH2 DatabaseType = "h2"
+	// DatabaseTypePostgres is a PostgreSQL database.
+	DatabaseTypePostgres DatabaseType = "postgres"
+	// DatabaseTypeMySQL is a MySQL database.
+	DatabaseTypeMySQL DatabaseType = "mysql"
+	// DatabaseTypeMSSQL is a Microsoft SQL Server database.
+	DatabaseTypeMSSQL DatabaseType = "mssql"
+	// DatabaseTypeOracle is an Oracle database.
+	DatabaseTypeOracle DatabaseType = "oracle"
+)
+
+const (
+	// DefaultDatabasePoolMinSize is the default minimum size of the connection pool.
+	DefaultDatabasePoolMinSize = 20
+	// DefaultDatabasePoolMaxSize is the default maximum size of the connection pool.
+	DefaultDatabasePoolMaxSize = 20
+)
+
+// JiraDatabaseSpec defines the database used by JIRA.
+type JiraDatabaseSpec struct {
+	// Type is the type of database, one of h2, postgres, mysql, mssql or oracle.
+	Type DatabaseType `json:"type"`
+
+	// Host is the hostname of the database server.
+	Host string `json:"host,omitempty"`
+
+	// Port is the port of the database server.
+	Port int32 `json:"port,omitempty"`
+
+	// Name is the name of the database, or the service name for Oracle.
+	Name string `json:"name,omitempty"`
+
+	// Schema is the database schema used by JIRA.
+	Schema string `json:"schema,omitempty"`
+
+	// PoolMinSize is the minimum size of the connection pool.
+	PoolMinSize int32 `json:"poolMinSize,omitempty"`
+
+	// PoolMaxSize is the maximum size of the connection pool.
+	PoolMaxSize int32 `json:"poolMaxSize,omitempty"`
+
+	// ValidationQuery is the query used to validate pooled connections.
+	ValidationQuery string `json:"validationQuery,omitempty"`
+
+	// CredentialsSecret is the name of the Secret holding the database
+	// credentials in the username and password keys. Defaults to SecretName.
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// SetDefaults sets the default values for the database spec and returns true
+// if the spec was changed.
+func (d *JiraDatabaseSpec) SetDefaults() bool {
+	changed := false
+	if len(d.Type) == 0 {
+		d.Type = DatabaseTypeH2
+		changed = true
+	}
+	if d.Port == 0 {
+		switch d.Type {
+		case DatabaseTypePostgres:
+			d.Port = 5432
+		case DatabaseTypeMySQL:
+			d.Port = 3306
+		case DatabaseTypeMSSQL:
+			d.Port = 1433
+		case DatabaseTypeOracle:
+			d.Port = 1521
+		}
+		changed = changed || d.Port != 0
+	}
+	if len(d.Schema) == 0 {
+		switch d.Type {
+		case DatabaseTypeH2:
+			d.Schema = "PUBLIC"
+		case DatabaseTypePostgres:
+			d.Schema = "public"
+		case DatabaseTypeMSSQL:
+			d.Schema = "dbo"
+		}
+		changed = changed || len(d.Schema) != 0
+	}
+	if d.PoolMinSize == 0 {
+		d.PoolMinSize = DefaultDatabasePoolMinSize
+		changed = true
+	}
+	if d.PoolMaxSize == 0 {
+		d.PoolMaxSize = DefaultDatabasePoolMaxSize
+		changed = true
+	}
+	if len(d.ValidationQuery) == 0 {
+		switch d.Type {
+		case DatabaseTypePostgres, DatabaseTypeMySQL, DatabaseTypeMSSQL:
+			d.ValidationQuery = "select 1"
+		case DatabaseTypeOracle:
+			d.ValidationQuery = "select 1 from dual"
+		}
+		changed = changed || len(d.ValidationQuery) != 0
+	}
+	return changed
+}
+
+const (
+	// DefaultBackupSchedule is the default schedule of backups, daily at 2am.
+	DefaultBackupSchedule = "0 2 * * *"
+	// DefaultBackupRetention is the default number of backups kept.
+	DefaultBackupRetention = 7
+	// DefaultBackupImage is the default image used to upload backups.
+	DefaultBackupImage = "minio/mc"
+)
+
+// JiraBackupMode is the way backups are taken.
+type JiraBackupMode string
+
+const (
+	// JiraBackupModeArchive archives JIRA Home and dumps the database to
+	// S3-compatible object storage.
+	JiraBackupModeArchive JiraBackupMode = "Archive"
+	// JiraBackupModeSnapshot stops JIRA and takes a VolumeSnapshot of the
+	// JIRA Home PVC. It is not supported for a cluster, as the snapshot does
+	// not cover the shared home.
+	JiraBackupModeSnapshot JiraBackupMode = "Snapshot"
+)
+
+// JiraBackupSpec defines scheduled backups of JIRA Home and the database.
+type JiraBackupSpec struct {
+	// Mode is the way backups are taken. One of Archive or Snapshot.
+	// Defaults to Archive.
+	Mode JiraBackupMode `json:"mode,omitempty"`
+
+	// Schedule is the cron schedule of the backups in the syntax of a
+	// CronJob schedule.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Suspend stops the scheduling of new backups.
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Retention is the number of backups kept in the bucket, or the number
+	// of snapshots kept. Older backups are removed after each successful
+	// backup.
+	Retention int32 `json:"retention,omitempty"`
+
+	// Image is the image of the MinIO client used to upload the backups.
+	Image string `json:"image,omitempty"`
+
+	// DumpImage is the image used to dump the database. Defaults to the
+	// postgres or mysql image for those databases and to the JIRA image for
+	// the embedded H2 database. Other databases are not dumped and must be
+	// backed up separately.
+	DumpImage string `json:"dumpImage,omitempty"`
+
+	// S3 is the object storage the backups are uploaded to. Required by the
+	// Archive mode.
+	S3 JiraBackupS3Spec `json:"s3,omitempty"`
+}
+
+// JiraBackupS3Spec defines the S3-compatible object storage of backups.
+type JiraBackupS3Spec struct {
+	// Endpoint is the URL of the S3 endpoint, e.g. https://s3.amazonaws.com
+	// or http://minio:9000.
+	Endpoint string `json:"endpoint"`
+
+	// Bucket is the name of the bucket the backups are uploaded to.
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to the backup paths in the bucket. Defaults to
+	// <namespace>/<name>.
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecret is the name of the Secret with the access key
+	// (access-key-id) and secret key (secret-access-key).
+	CredentialsSecret string `json:"credentialsSecret"`
 }
 
-// SetDefaults sets the default vaules for the cuberite spec and returns true if the spec was changed
+// SetDefaults sets the default values of the backup spec and returns true if
+// the spec was changed.
+func (b *JiraBackupSpec) SetDefaults(j *Jira) bool {
+	changed := false
+	if len(b.Mode) == 0 {
+		b.Mode = JiraBackupModeArchive
+		changed = true
+	}
+	if len(b.Schedule) == 0 {
+		b.Schedule = DefaultBackupSchedule
+		changed = true
+	}
+	if b.Retention == 0 {
+		b.Retention = DefaultBackupRetention
+		changed = true
+	}
+	if len(b.Image) == 0 {
+		b.Image = DefaultBackupImage
+		changed = true
+	}
+	if len(b.S3.Prefix) == 0 {
+		b.S3.Prefix = j.Namespace + "/" + j.Name
+		changed = true
+	}
+	return changed
+}
+
+const (
+	// DefaultMonitoringPort is the default port of the JMX exporter.
+	DefaultMonitoringPort = 9404
+	// DefaultMonitoringJMXPort is the default port of the JMX remote
+	// connector of JIRA used by the sidecar.
+	DefaultMonitoringJMXPort = 9010
+	// DefaultMonitoringAgentURL is the default download URL of the JMX
+	// exporter Java agent.
+	DefaultMonitoringAgentURL = "https://repo1.maven.org/maven2/io/prometheus/jmx/jmx_prometheus_javaagent/0.3.1/jmx_prometheus_javaagent-0.3.1.jar"
+	// DefaultMonitoringImage is the default image of the JMX exporter
+	// sidecar.
+	DefaultMonitoringImage = "sscaling/jmx-prometheus-exporter:0.3.0"
+)
+
+// JiraMonitoringMode is the way the JMX exporter is run.
+type JiraMonitoringMode string
+
+const (
+	// JiraMonitoringModeAgent runs the JMX exporter as a Java agent in the
+	// JVM of JIRA.
+	JiraMonitoringModeAgent JiraMonitoringMode = "Agent"
+	// JiraMonitoringModeSidecar runs the JMX exporter in a sidecar container
+	// that connects to the JMX remote connector of JIRA.
+	JiraMonitoringModeSidecar JiraMonitoringMode = "Sidecar"
+)
+
+// JiraMonitoringSpec defines a JMX exporter that serves the JVM, Tomcat and
+// JIRA metrics in the Prometheus format. The JIRA MBeans are only available
+// if JMX monitoring is enabled in the JIRA administration.
+type JiraMonitoringSpec struct {
+	// Mode is the way the JMX exporter is run, either Agent or Sidecar.
+	// Defaults to Agent.
+	Mode JiraMonitoringMode `json:"mode,omitempty"`
+
+	// Port is the port the metrics are served on. Defaults to 9404.
+	Port int32 `json:"port,omitempty"`
+
+	// JMXPort is the port of the JMX remote connector of JIRA, which is only
+	// enabled in the Sidecar mode. Defaults to 9010.
+	JMXPort int32 `json:"jmxPort,omitempty"`
+
+	// AgentURL is the download URL of the JMX exporter Java agent used in the
+	// Agent mode.
+	AgentURL string `json:"agentURL,omitempty"`
+
+	// Image is the image of the JMX exporter sidecar used in the Sidecar
+	// mode.
+	Image string `json:"image,omitempty"`
+
+	// Config is the configuration of the JMX exporter in YAML. The host and
+	// port of the sidecar are set by the operator. Defaults to a
+	// configuration exporting the JVM, Tomcat and JIRA MBeans.
+	Config string `json:"config,omitempty"`
+
+	// ServiceMonitor creates a ServiceMonitor for the Prometheus Operator
+	// that scrapes the JMX exporter.
+	ServiceMonitor *JiraServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+}
+
+// JiraServiceMonitorSpec defines the ServiceMonitor of the JMX exporter.
+type JiraServiceMonitorSpec struct {
+	// Interval is the scrape interval, e.g. 30s. The default interval of
+	// Prometheus is used if empty.
+	Interval string `json:"interval,omitempty"`
+
+	// Labels are added to the ServiceMonitor, e.g. to match the
+	// serviceMonitorSelector of a Prometheus.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SetDefaults sets the default values for the monitoring spec and returns
+// true if the spec was changed.
+func (m *JiraMonitoringSpec) SetDefaults() bool {
+	changed := false
+	if len(m.Mode) == 0 {
+		m.Mode = JiraMonitoringModeAgent
+		changed = true
+	}
+	if m.Port == 0 {
+		m.Port = DefaultMonitoringPort
+		changed = true
+	}
+	if m.Mode == JiraMonitoringModeAgent && len(m.AgentURL) == 0 {
+		m.AgentURL = DefaultMonitoringAgentURL
+		changed = true
+	}
+	if m.Mode == JiraMonitoringModeSidecar {
+		if m.JMXPort == 0 {
+			m.JMXPort = DefaultMonitoringJMXPort
+			changed = true
+		}
+		if len(m.Image) == 0 {
+			m.Image = DefaultMonitoringImage
+			changed = true
+		}
+	}
+	return changed
+}
+
+// JiraUpgradePolicy defines the policy for JIRA version upgrades.
+type JiraUpgradePolicy struct {
+	// Backup enables a backup of JIRA Home before the new version is rolled
+	// out. This field is ignored if no PVC spec is defined.
+	Backup bool `json:"backup,omitempty"`
+}
+
+// SetDefaults sets the default values for the JIRA spec and returns true if the spec was changed
 func (j *Jira) SetDefaults() bool {
 	changed := false
 	if len(j.Spec.BaseImage) == 0 {
@@ -98,22 +689,489 @@ func (j *Jira) SetDefaults() bool {
 		j.Spec.DataMountPath = DefaultDataMountPath
 		changed = true
 	}
+	if s := j.Spec.Storage; s != nil && s.LocalHome != nil {
+		if len(s.LocalHome.MountPath) == 0 {
+			s.LocalHome.MountPath = j.Spec.DataMountPath
+			changed = true
+		} else if s.LocalHome.MountPath != j.Spec.DataMountPath {
+			j.Spec.DataMountPath = s.LocalHome.MountPath
+			changed = true
+		}
+	}
 	if len(j.Spec.SecretName) == 0 {
 		j.Spec.SecretName = j.Name
 		changed = true
 	}
+	if e := j.Spec.Expose; e != nil {
+		if len(e.ServiceType) == 0 {
+			e.ServiceType = v1.ServiceTypeNodePort
+			changed = true
+		}
+		if len(e.ContextPath) > 0 {
+			cp := "/" + strings.Trim(e.ContextPath, "/")
+			if cp == "/" {
+				cp = ""
+			}
+			if cp != e.ContextPath {
+				e.ContextPath = cp
+				changed = true
+			}
+		}
+		if e.Ingress != nil && len(e.Ingress.Path) == 0 {
+			e.Ingress.Path = "/"
+			if len(e.ContextPath) > 0 {
+				e.Ingress.Path = e.ContextPath
+			}
+			changed = true
+		}
+	}
+	if j.Spec.Probes == nil {
+		j.Spec.Probes = &JiraProbeSpec{}
+		changed = true
+	}
+	changed = j.Spec.Probes.SetDefaults() || changed
+	if j.Spec.Storage == nil {
+		j.Spec.Storage = &JiraStorageSpec{}
+		changed = true
+	}
+	if len(j.Spec.Storage.ReclaimPolicy) == 0 {
+		j.Spec.Storage.ReclaimPolicy = StorageReclaimRetain
+		changed = true
+	}
+	if j.Spec.Backup != nil {
+		changed = j.Spec.Backup.SetDefaults(j) || changed
+	}
+	if j.Spec.Monitoring != nil {
+		changed = j.Spec.Monitoring.SetDefaults() || changed
+	}
+	if j.Spec.Replicas == nil {
+		replicas := int32(1)
+		j.Spec.Replicas = &replicas
+		changed = true
+	}
+	if j.IsClustered() {
+		if len(j.Spec.Storage.SharedHome.MountPath) == 0 {
+			j.Spec.Storage.SharedHome.MountPath = DefaultSharedHomeMountPath
+			changed = true
+		}
+		if j.Spec.Cluster == nil {
+			j.Spec.Cluster = &JiraClusterSpec{}
+			changed = true
+		}
+		changed = j.Spec.Cluster.SetDefaults() || changed
+	}
+	if db := j.Spec.Database; db != nil {
+		changed = db.SetDefaults() || changed
+		if db.Type != DatabaseTypeH2 && len(db.CredentialsSecret) == 0 {
+			db.CredentialsSecret = j.Spec.SecretName
+			changed = true
+		}
+	}
 	return changed
 }
 
-// IsPVEnabled shortcut fucntion to determine PV status.
+// IsClustered returns true if JIRA runs as a Data Center cluster with a
+// shared home.
+func (j *Jira) IsClustered() bool {
+	return j.Spec.Storage != nil && j.Spec.Storage.SharedHome != nil
+}
+
+// IsPVEnabled shortcut function to determine PV status.
 func (j *Jira) IsPVEnabled() bool {
+	return j.LocalHomeClaimSpec() != nil
+}
+
+// LocalHomeClaimSpec returns the PVC spec of the local home, or nil if the
+// local home is an emptyDir.
+func (j *Jira) LocalHomeClaimSpec() *v1.PersistentVolumeClaimSpec {
+	if s := j.Spec.Storage; s != nil && s.LocalHome != nil {
+		return s.LocalHome.PersistentVolumeClaimSpec
+	}
 	if podPolicy := j.Spec.Pod; podPolicy != nil {
-		return podPolicy.PersistentVolumeClaimSpec != nil
+		return podPolicy.PersistentVolumeClaimSpec
 	}
-	return false
+	return nil
+}
+
+// JiraPhase is the lifecycle phase of a JIRA instance.
+type JiraPhase string
+
+const (
+	// JiraPhasePending means the resource has been accepted but nothing has been created yet.
+	JiraPhasePending JiraPhase = "Pending"
+	// JiraPhaseCreating means the resources are created but JIRA is not ready yet.
+	JiraPhaseCreating JiraPhase = "Creating"
+	// JiraPhaseRunning means all resources are ready and JIRA is serving requests.
+	JiraPhaseRunning JiraPhase = "Running"
+	// JiraPhaseFailed means the resources cannot be reconciled because the
+	// spec is invalid. Transient errors are reported with the
+	// ReconcileFailed condition instead.
+	JiraPhaseFailed JiraPhase = "Failed"
+	// JiraPhaseTerminating means the resource is being deleted.
+	JiraPhaseTerminating JiraPhase = "Terminating"
+)
+
+// JiraConditionType is the type of a JIRA condition.
+type JiraConditionType string
+
+const (
+	// JiraConditionConfigMapReady indicates the database ConfigMap exists.
+	JiraConditionConfigMapReady JiraConditionType = "ConfigMapReady"
+	// JiraConditionStorageBound indicates the data volume is bound.
+	JiraConditionStorageBound JiraConditionType = "StorageBound"
+	// JiraConditionPodReady indicates the JIRA Pod is ready.
+	JiraConditionPodReady JiraConditionType = "PodReady"
+	// JiraConditionServiceReady indicates the JIRA Service exists.
+	JiraConditionServiceReady JiraConditionType = "ServiceReady"
+	// JiraConditionHealthy indicates the JIRA status endpoint passes the
+	// readiness probe of every JIRA container.
+	JiraConditionHealthy JiraConditionType = "Healthy"
+	// JiraConditionRolloutPaused indicates a rolling restart of the cluster
+	// nodes is paused because a node did not come back or its index cannot be
+	// checked.
+	JiraConditionRolloutPaused JiraConditionType = "RolloutPaused"
+	// JiraConditionReclaimFailed indicates the storage of a deleted Jira
+	// resource cannot be reclaimed according to its reclaim policy.
+	JiraConditionReclaimFailed JiraConditionType = "ReclaimFailed"
+	// JiraConditionReconcileFailed indicates the last reconcile of the
+	// resources failed.
+	JiraConditionReconcileFailed JiraConditionType = "ReconcileFailed"
+)
+
+// JiraCondition describes the state of a JIRA instance at a certain point.
+type JiraCondition struct {
+	// Type of the condition.
+	Type JiraConditionType `json:"type"`
+
+	// Status of the condition, one of True, False or Unknown.
+	Status v1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime is the last time the condition changed status.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a one-word CamelCase reason for the last transition.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the last transition.
+	Message string `json:"message,omitempty"`
 }
 
 // JiraStatus resource
 type JiraStatus struct {
-	// Fill me
+	// Phase is the current lifecycle phase of the JIRA instance.
+	Phase JiraPhase `json:"phase,omitempty"`
+
+	// Conditions are the latest observations of the JIRA resources.
+	Conditions []JiraCondition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation last processed by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Endpoint is the in-cluster URL of the JIRA service.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// URL is the external URL of JIRA if it is exposed by an Ingress or Route.
+	URL string `json:"url,omitempty"`
+
+	// Image is the image currently running in the JIRA Pod.
+	Image string `json:"image,omitempty"`
+
+	// Version is the JIRA version currently deployed.
+	Version string `json:"version,omitempty"`
+
+	// Replicas is the number of JIRA nodes.
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of ready JIRA nodes.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Selector is the label selector of the JIRA pods, used by the scale
+	// subresource.
+	Selector string `json:"selector,omitempty"`
+
+	// Upgrade is the state of the current or last version upgrade.
+	Upgrade *JiraUpgradeStatus `json:"upgrade,omitempty"`
+
+	// Backup is the state of the scheduled backups.
+	Backup *JiraBackupStatus `json:"backup,omitempty"`
+
+	// Rollout is the state of the rolling restart of the cluster nodes in
+	// progress.
+	Rollout *JiraRolloutStatus `json:"rollout,omitempty"`
+}
+
+// JiraRolloutPhase is the phase of the node being restarted by a rolling
+// restart.
+type JiraRolloutPhase string
+
+const (
+	// JiraRolloutPhaseDraining means the node is removed from the Service.
+	JiraRolloutPhaseDraining JiraRolloutPhase = "Draining"
+	// JiraRolloutPhaseRestarting means the node is restarted and JIRA is
+	// waited for to be running with an up to date index.
+	JiraRolloutPhaseRestarting JiraRolloutPhase = "Restarting"
+)
+
+// JiraRolloutStatus is the state of a rolling restart of the cluster nodes.
+// Nodes are restarted one at a time from the highest ordinal down.
+type JiraRolloutStatus struct {
+	// Revision is the hash of the pod template rolled out.
+	Revision string `json:"revision"`
+
+	// Partition is the ordinal from which on nodes run the new revision.
+	Partition int32 `json:"partition"`
+
+	// Node is the name of the node currently restarted.
+	Node string `json:"node,omitempty"`
+
+	// Phase is the phase of the node currently restarted.
+	Phase JiraRolloutPhase `json:"phase,omitempty"`
+
+	// PhaseStartTime is the time the current phase started.
+	PhaseStartTime metav1.Time `json:"phaseStartTime,omitempty"`
+}
+
+// JiraBackupStatus is the state of the scheduled backups.
+type JiraBackupStatus struct {
+	// LastScheduleTime is the time the last backup was started.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastSuccessTime is the time the last successful backup completed.
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// LastFailureTime is the time the last backup failed.
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// Snapshot is the snapshot backup in progress.
+	Snapshot *JiraSnapshotBackupStatus `json:"snapshot,omitempty"`
+}
+
+// JiraSnapshotBackupPhase is the phase of a snapshot backup.
+type JiraSnapshotBackupPhase string
+
+const (
+	// JiraSnapshotBackupPhaseQuiescing means JIRA is being stopped.
+	JiraSnapshotBackupPhaseQuiescing JiraSnapshotBackupPhase = "Quiescing"
+	// JiraSnapshotBackupPhaseSnapshotting means the snapshot is being taken.
+	JiraSnapshotBackupPhaseSnapshotting JiraSnapshotBackupPhase = "Snapshotting"
+)
+
+// JiraSnapshotBackupStatus is the state of a snapshot backup in progress.
+type JiraSnapshotBackupStatus struct {
+	// Name is the name of the VolumeSnapshot.
+	Name string `json:"name"`
+
+	// Phase is the current phase of the snapshot backup.
+	Phase JiraSnapshotBackupPhase `json:"phase"`
+
+	// StartTime is the time the snapshot backup was started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// JiraUpgradePhase is the phase of a JIRA version upgrade.
+type JiraUpgradePhase string
+
+const (
+	// JiraUpgradePhaseScalingDown means JIRA is being stopped.
+	JiraUpgradePhaseScalingDown JiraUpgradePhase = "ScalingDown"
+	// JiraUpgradePhaseBackingUp means JIRA Home is being backed up.
+	JiraUpgradePhaseBackingUp JiraUpgradePhase = "BackingUp"
+	// JiraUpgradePhaseRolling means JIRA is being started with the new image.
+	JiraUpgradePhaseRolling JiraUpgradePhase = "Rolling"
+	// JiraUpgradePhaseWaiting means JIRA is running its upgrade tasks.
+	JiraUpgradePhaseWaiting JiraUpgradePhase = "WaitingForUpgradeTasks"
+	// JiraUpgradePhaseCompleted means the upgrade has finished successfully.
+	JiraUpgradePhaseCompleted JiraUpgradePhase = "Completed"
+	// JiraUpgradePhaseFailed means the upgrade was rejected or did not finish.
+	// The previous version is only deployed again if the new image was not
+	// rolled out yet, otherwise JIRA Home may already have been upgraded.
+	JiraUpgradePhaseFailed JiraUpgradePhase = "Failed"
+)
+
+// JiraUpgradeStatus is the state of a JIRA version upgrade.
+type JiraUpgradeStatus struct {
+	// FromVersion is the version that was running when the upgrade started.
+	FromVersion string `json:"fromVersion"`
+
+	// ToVersion is the version being upgraded to.
+	ToVersion string `json:"toVersion"`
+
+	// Phase is the current phase of the upgrade.
+	Phase JiraUpgradePhase `json:"phase"`
+
+	// Reason is a one-word CamelCase reason for a failed upgrade.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the upgrade outcome.
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the upgrade was started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// PhaseStartTime is the time the current phase of the upgrade started.
+	PhaseStartTime *metav1.Time `json:"phaseStartTime,omitempty"`
+
+	// CompletionTime is the time the upgrade completed or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// IsFinished returns true if the upgrade has completed or failed.
+func (u *JiraUpgradeStatus) IsFinished() bool {
+	return u.Phase == JiraUpgradePhaseCompleted || u.Phase == JiraUpgradePhaseFailed
+}
+
+// IsUpgradeBackupEnabled shortcut function to determine if a backup is taken
+// before an upgrade.
+func (j *Jira) IsUpgradeBackupEnabled() bool {
+	return j.Spec.Upgrade != nil && j.Spec.Upgrade.Backup && j.IsPVEnabled()
+}
+
+// GetCondition returns the condition of the given type or nil if it is not set.
+func (s *JiraStatus) GetCondition(t JiraConditionType) *JiraCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of the given type. The
+// transition time is only changed when the status changes.
+func (s *JiraStatus) SetCondition(t JiraConditionType, status v1.ConditionStatus, reason, message string) {
+	if c := s.GetCondition(t); c != nil {
+		if c.Status != status {
+			c.Status = status
+			c.LastTransitionTime = metav1.Now()
+		}
+		c.Reason = reason
+		c.Message = message
+		return
+	}
+	s.Conditions = append(s.Conditions, JiraCondition{
+		Type:               t,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// IsConditionTrue returns true if the condition of the given type has status True.
+func (s *JiraStatus) IsConditionTrue(t JiraConditionType) bool {
+	c := s.GetCondition(t)
+	return c != nil && c.Status == v1.ConditionTrue
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// JiraRestoreList resource
+type JiraRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []JiraRestore `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// JiraRestore resource restores a Jira resource from a backup.
+type JiraRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              JiraRestoreSpec   `json:"spec"`
+	Status            JiraRestoreStatus `json:"status,omitempty"`
+}
+
+// JiraRestoreSpec defines the backup to restore and the Jira resource it is
+// restored to.
+type JiraRestoreSpec struct {
+	// JiraName is the name of the Jira resource in the same namespace that is
+	// restored.
+	JiraName string `json:"jiraName"`
+
+	// Backup is the name of the backup to restore, i.e. the time stamped
+	// directory below the backup prefix in the bucket.
+	Backup string `json:"backup"`
+
+	// S3 is the object storage the backup is downloaded from. Defaults to the
+	// backup storage of the Jira resource.
+	S3 *JiraBackupS3Spec `json:"s3,omitempty"`
+
+	// Image is the image of the MinIO client used to download the backup.
+	Image string `json:"image,omitempty"`
+
+	// RestoreImage is the image used to restore the database. Defaults to
+	// the dump image of the backup spec of the Jira resource.
+	RestoreImage string `json:"restoreImage,omitempty"`
+}
+
+// SetDefaults sets the default values of the restore spec and returns true if
+// the spec was changed.
+func (r *JiraRestore) SetDefaults() bool {
+	changed := false
+	if len(r.Spec.Image) == 0 {
+		r.Spec.Image = DefaultBackupImage
+		changed = true
+	}
+	return changed
+}
+
+// JiraRestorePhase is the phase of a restore.
+type JiraRestorePhase string
+
+const (
+	// JiraRestorePhasePending means the restore has not started yet.
+	JiraRestorePhasePending JiraRestorePhase = "Pending"
+	// JiraRestorePhaseStopping means JIRA is being stopped.
+	JiraRestorePhaseStopping JiraRestorePhase = "Stopping"
+	// JiraRestorePhaseRestoring means JIRA Home and the database are being
+	// restored.
+	JiraRestorePhaseRestoring JiraRestorePhase = "Restoring"
+	// JiraRestorePhaseStarting means JIRA is being started.
+	JiraRestorePhaseStarting JiraRestorePhase = "Starting"
+	// JiraRestorePhaseCompleted means the restore has completed.
+	JiraRestorePhaseCompleted JiraRestorePhase = "Completed"
+	// JiraRestorePhaseFailed means the restore has failed.
+	JiraRestorePhaseFailed JiraRestorePhase = "Failed"
+)
+
+const (
+	// JiraRestoreReasonInvalidSpec means the restore spec is incomplete.
+	JiraRestoreReasonInvalidSpec = "InvalidSpec"
+	// JiraRestoreReasonJiraNotFound means the Jira resource does not exist.
+	JiraRestoreReasonJiraNotFound = "JiraNotFound"
+	// JiraRestoreReasonNoBackupStorage means no object storage is defined in
+	// the restore or the Jira resource.
+	JiraRestoreReasonNoBackupStorage = "NoBackupStorage"
+	// JiraRestoreReasonNoPersistentStorage means the embedded database cannot
+	// be restored as JIRA Home is not persistent.
+	JiraRestoreReasonNoPersistentStorage = "NoPersistentStorage"
+	// JiraRestoreReasonRestoreJobFailed means the restore Job failed.
+	JiraRestoreReasonRestoreJobFailed = "RestoreJobFailed"
+	// JiraRestoreReasonRestoreJobNotFound means the restore Job was deleted
+	// before it finished.
+	JiraRestoreReasonRestoreJobNotFound = "RestoreJobNotFound"
+)
+
+// JiraRestoreStatus is the observed state of a restore.
+type JiraRestoreStatus struct {
+	// Phase is the current phase of the restore.
+	Phase JiraRestorePhase `json:"phase,omitempty"`
+
+	// Reason is a machine readable reason for a failed restore.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the restore state.
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the restore was started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the restore completed or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// IsFinished returns true if the restore has completed or failed.
+func (s *JiraRestoreStatus) IsFinished() bool {
+	return s.Phase == JiraRestorePhaseCompleted || s.Phase == JiraRestorePhaseFailed
 }
@@ -0,0 +1,209 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// The validation rules are shared by the admission webhook and the operator,
+// which checks the resources again in case the webhook is not deployed. They
+// expect a defaulted resource.
+
+// ValidateStorage validates the volumes and the reclaim policy.
+func (j *Jira) ValidateStorage() field.ErrorList {
+	s := j.Spec.Storage
+	if s == nil {
+		return nil
+	}
+	path := field.NewPath("spec", "storage")
+
+	var errs field.ErrorList
+	switch s.ReclaimPolicy {
+	case StorageReclaimRetain, StorageReclaimDelete, StorageReclaimSnapshot:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("reclaimPolicy"), s.ReclaimPolicy, []string{
+			string(StorageReclaimRetain), string(StorageReclaimDelete), string(StorageReclaimSnapshot),
+		}))
+	}
+	if s.DataSource != nil && len(s.DataSource.VolumeSnapshotName) == 0 {
+		errs = append(errs, field.Required(path.Child("dataSource", "volumeSnapshotName"), "the name of the VolumeSnapshot is required"))
+	}
+	for _, name := range []string{"localHome", "sharedHome"} {
+		vol := s.LocalHome
+		if name == "sharedHome" {
+			vol = s.SharedHome
+		}
+		if vol == nil {
+			continue
+		}
+		if vol.PersistentVolumeClaimSpec != nil && vol.EmptyDir != nil {
+			errs = append(errs, field.Forbidden(path.Child(name), "must not define both a persistentVolumeClaimSpec and an emptyDir"))
+		}
+		if vol.PersistentVolumeClaimSpec != nil {
+			errs = append(errs, ValidateClaimSpec(vol.PersistentVolumeClaimSpec, path.Child(name, "persistentVolumeClaimSpec"))...)
+		}
+		if len(vol.MountPath) > 0 && !strings.HasPrefix(vol.MountPath, "/") {
+			errs = append(errs, field.Invalid(path.Child(name, "mountPath"), vol.MountPath, "must be an absolute path"))
+		}
+	}
+	return errs
+}
+
+// ValidateCluster validates the number of replicas and the cluster ports. More
+// than one node requires a ReadWriteMany shared home and an external database.
+func (j *Jira) ValidateCluster() field.ErrorList {
+	path := field.NewPath("spec")
+	n := int32(1)
+	if j.Spec.Replicas != nil {
+		n = *j.Spec.Replicas
+	}
+	if n < 0 {
+		return field.ErrorList{field.Invalid(path.Child("replicas"), n, "must be greater than or equal to 0")}
+	}
+
+	var errs field.ErrorList
+	if c := j.Spec.Cluster; c != nil && j.IsClustered() {
+		errs = append(errs, ValidatePort(c.EhcacheListenerPort, path.Child("cluster", "ehcacheListenerPort"))...)
+		errs = append(errs, ValidatePort(c.EhcacheObjectPort, path.Child("cluster", "ehcacheObjectPort"))...)
+		if c.EhcacheListenerPort == c.EhcacheObjectPort {
+			errs = append(errs, field.Invalid(path.Child("cluster", "ehcacheObjectPort"), c.EhcacheObjectPort, "must differ from ehcacheListenerPort"))
+		}
+		if c.DrainSeconds < 0 {
+			errs = append(errs, field.Invalid(path.Child("cluster", "drainSeconds"), c.DrainSeconds, "must be greater than or equal to 0"))
+		}
+	}
+	if n <= 1 {
+		return errs
+	}
+	if !j.IsClustered() {
+		return append(errs, field.Required(path.Child("storage", "sharedHome"), fmt.Sprintf("%d replicas require a shared home", n)))
+	}
+	if j.Spec.Database == nil || j.Spec.Database.Type == DatabaseTypeH2 {
+		errs = append(errs, field.Required(path.Child("database"), fmt.Sprintf("%d replicas require an external database", n)))
+	}
+	if !hasAccessMode(j.Spec.Storage.SharedHome.PersistentVolumeClaimSpec, v1.ReadWriteMany) {
+		errs = append(errs, field.Invalid(path.Child("storage", "sharedHome"), n,
+			fmt.Sprintf("%d replicas require a persistentVolumeClaimSpec with the ReadWriteMany access mode", n)))
+	}
+	return errs
+}
+
+// ValidateResources rejects negative quantities and requests that exceed
+// their limit.
+func ValidateResources(r v1.ResourceRequirements, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for name, q := range r.Limits {
+		if q.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child("limits").Key(string(name)), q.String(), "must be greater than or equal to 0"))
+		}
+	}
+	for name, q := range r.Requests {
+		if q.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child("requests").Key(string(name)), q.String(), "must be greater than or equal to 0"))
+		}
+		if limit, ok := r.Limits[name]; ok && q.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(path.Child("requests").Key(string(name)), q.String(),
+				fmt.Sprintf("must be less than or equal to the %s limit of %s", name, limit.String())))
+		}
+	}
+	return errs
+}
+
+// ValidateClaimSpec requires a positive storage request and an access mode.
+func ValidateClaimSpec(spec *v1.PersistentVolumeClaimSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(spec.AccessModes) == 0 {
+		errs = append(errs, field.Required(path.Child("accessModes"), "at least one access mode is required"))
+	}
+	storage := path.Child("resources", "requests").Key(string(v1.ResourceStorage))
+	if q, ok := spec.Resources.Requests[v1.ResourceStorage]; !ok {
+		errs = append(errs, field.Required(storage, "the storage size of the volume is required"))
+	} else if q.Sign() <= 0 {
+		errs = append(errs, field.Invalid(storage, q.String(), "must be greater than 0"))
+	}
+	errs = append(errs, ValidateResources(spec.Resources, path.Child("resources"))...)
+	return errs
+}
+
+// ValidatePort requires a valid TCP port.
+func ValidatePort(port int32, path *field.Path) field.ErrorList {
+	if port < 1 || port > 65535 {
+		return field.ErrorList{field.Invalid(path, port, "must be between 1 and 65535")}
+	}
+	return nil
+}
+
+// hasAccessMode returns true if the claim spec requests the access mode.
+func hasAccessMode(spec *v1.PersistentVolumeClaimSpec, mode v1.PersistentVolumeAccessMode) bool {
+	if spec == nil {
+		return false
+	}
+	for _, m := range spec.AccessModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseVersion parses a JIRA version of the form major.minor.patch. Missing
+// minor or patch numbers are treated as zero.
+func ParseVersion(version string) ([3]int, error) {
+	var v [3]int
+	parts := strings.SplitN(version, ".", 3)
+	for i, p := range parts {
+		if i == 2 {
+			p = strings.SplitN(p, "-", 2)[0]
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q", version)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// ValidateUpgrade returns an error if JIRA cannot be upgraded from one version
+// to the other. Downgrades are not supported and major versions may not be
+// skipped.
+func ValidateUpgrade(from, to string) error {
+	f, err := ParseVersion(from)
+	if err != nil {
+		return err
+	}
+	t, err := ParseVersion(to)
+	if err != nil {
+		return err
+	}
+	for i := range f {
+		if t[i] > f[i] {
+			break
+		}
+		if t[i] < f[i] {
+			return fmt.Errorf("downgrade from %s to %s is not supported", from, to)
+		}
+	}
+	if t[0] > f[0]+1 {
+		return fmt.Errorf("upgrade from %s to %s skips major version %d, upgrade to %d.x first", from, to, f[0]+1, f[0]+1)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    [3]int
+		wantErr bool
+	}{
+		{version: "7.12.1", want: [3]int{7, 12, 1}},
+		{version: "7.12.1-jdk8", want: [3]int{7, 12, 1}},
+		{version: "8.0", want: [3]int{8, 0, 0}},
+		{version: "8", want: [3]int{8, 0, 0}},
+		{version: "", wantErr: true},
+		{version: "latest", wantErr: true},
+		{version: "7.x", wantErr: true},
+		{version: "7.12-jdk8", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestValidateUpgrade(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  bool
+	}{
+		{from: "7.12.1", to: "7.12.1"},
+		{from: "7.12.1", to: "7.12.3"},
+		{from: "7.12.1", to: "7.13.0"},
+		{from: "7.12.1", to: "8.0.0"},
+		{from: "7.12.1-jdk8", to: "7.13.0"},
+		{from: "7.12", to: "7.12.1"},
+		{from: "7.12.1", to: "7.12.0", wantErr: true},
+		{from: "7.12.1", to: "7.11.9", wantErr: true},
+		{from: "8.0.0", to: "7.13.0", wantErr: true},
+		{from: "7.12.1", to: "9.0.0", wantErr: true},
+		{from: "6.4.14", to: "8.0.0", wantErr: true},
+		{from: "latest", to: "7.12.1", wantErr: true},
+		{from: "7.12.1", to: "latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := ValidateUpgrade(tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateUpgrade(%q, %q) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+}
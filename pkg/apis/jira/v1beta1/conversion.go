@@ -0,0 +1,292 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// v1alpha1SpecAnnotation keeps the v1alpha1 fields of a converted Jira
+// resource that have no exact counterpart in v1beta1, so that converting the
+// resource back to v1alpha1 restores them.
+const v1alpha1SpecAnnotation = "app.redhat.com/v1alpha1-spec"
+
+// v1alpha1SpecFields are the v1alpha1 fields kept in the annotation.
+type v1alpha1SpecFields struct {
+	DataMountPath string                    `json:"data_mount_path,omitempty"`
+	Pod           *v1alpha1.JiraPodPolicy   `json:"pod,omitempty"`
+	Storage       *v1alpha1.JiraStorageSpec `json:"storage,omitempty"`
+}
+
+// ConvertJiraFromV1alpha1 converts a v1alpha1 Jira resource. The image
+// fields are nested, the data mount path and the deprecated PVC spec of the
+// pod policy become the local home and the pool settings of the database are
+// nested. If the v1alpha1 spec cannot be restored from the result, the fields
+// that differ are kept in an annotation.
+func ConvertJiraFromV1alpha1(in *v1alpha1.Jira) (*Jira, error) {
+	out, err := convertJiraFromV1alpha1(in)
+	if err != nil {
+		return nil, err
+	}
+	back, err := ConvertJiraToV1alpha1(out)
+	if err != nil {
+		return nil, err
+	}
+	if equality.Semantic.DeepEqual(back.Spec, in.Spec) {
+		return out, nil
+	}
+	data, err := json.Marshal(v1alpha1SpecFields{
+		DataMountPath: in.Spec.DataMountPath,
+		Pod:           in.Spec.Pod,
+		Storage:       in.Spec.Storage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Annotations == nil {
+		out.Annotations = make(map[string]string)
+	}
+	out.Annotations[v1alpha1SpecAnnotation] = string(data)
+	return out, nil
+}
+
+// convertJiraFromV1alpha1 converts the fields of a v1alpha1 Jira resource.
+func convertJiraFromV1alpha1(in *v1alpha1.Jira) (*Jira, error) {
+	out := &Jira{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.APIVersion = SchemeGroupVersion.String()
+	removeAnnotation(&out.ObjectMeta.Annotations)
+	if err := convertJSON(in.Status, &out.Status); err != nil {
+		return nil, err
+	}
+
+	spec := &out.Spec
+	spec.Image = JiraImageSpec{Repository: in.Spec.BaseImage, Tag: in.Spec.BaseImageVersion}
+	if in.Spec.Replicas != nil {
+		replicas := *in.Spec.Replicas
+		spec.Replicas = &replicas
+	}
+	spec.ConfigMapName = in.Spec.ConfigMapName
+	spec.SecretName = in.Spec.SecretName
+	if in.Spec.Pod != nil {
+		spec.Resources = *in.Spec.Pod.Resources.DeepCopy()
+	}
+	for _, f := range []struct{ in, out interface{} }{
+		{in.Spec.Cluster, &spec.Cluster},
+		{in.Spec.Upgrade, &spec.Upgrade},
+		{in.Spec.Expose, &spec.Expose},
+		{in.Spec.Probes, &spec.Probes},
+		{in.Spec.Storage, &spec.Storage},
+		{in.Spec.Backup, &spec.Backup},
+		{in.Spec.Monitoring, &spec.Monitoring},
+	} {
+		if err := convertJSON(f.in, f.out); err != nil {
+			return nil, err
+		}
+	}
+
+	localHome := func() *JiraVolumeSpec {
+		if spec.Storage == nil {
+			spec.Storage = &JiraStorageSpec{}
+		}
+		if spec.Storage.LocalHome == nil {
+			spec.Storage.LocalHome = &JiraVolumeSpec{}
+		}
+		return spec.Storage.LocalHome
+	}
+	// The PVC spec of the pod policy is ignored if a local home is defined.
+	if p := in.Spec.Pod; p != nil && p.PersistentVolumeClaimSpec != nil && (in.Spec.Storage == nil || in.Spec.Storage.LocalHome == nil) {
+		localHome().PersistentVolumeClaimSpec = p.PersistentVolumeClaimSpec.DeepCopy()
+	}
+	if len(in.Spec.DataMountPath) > 0 {
+		if lh := localHome(); len(lh.MountPath) == 0 {
+			lh.MountPath = in.Spec.DataMountPath
+		}
+	}
+
+	if db := in.Spec.Database; db != nil {
+		spec.Database = &JiraDatabaseSpec{
+			Type:              DatabaseType(db.Type),
+			Host:              db.Host,
+			Port:              db.Port,
+			Name:              db.Name,
+			Schema:            db.Schema,
+			CredentialsSecret: db.CredentialsSecret,
+		}
+		if db.PoolMinSize != 0 || db.PoolMaxSize != 0 || len(db.ValidationQuery) > 0 {
+			spec.Database.Pool = &JiraDatabasePoolSpec{
+				MinSize:         db.PoolMinSize,
+				MaxSize:         db.PoolMaxSize,
+				ValidationQuery: db.ValidationQuery,
+			}
+		}
+	}
+	return out, nil
+}
+
+// ConvertJiraToV1alpha1 converts a Jira resource to v1alpha1. The v1alpha1
+// fields kept in the annotation by ConvertJiraFromV1alpha1 are restored, as
+// long as they still convert to the v1beta1 spec of the resource.
+func ConvertJiraToV1alpha1(in *Jira) (*v1alpha1.Jira, error) {
+	out, err := convertJiraToV1alpha1(in)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := in.Annotations[v1alpha1SpecAnnotation]
+	if !ok {
+		return out, nil
+	}
+	var fields v1alpha1SpecFields
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return out, nil
+	}
+	restored := out.DeepCopy()
+	restored.Spec.DataMountPath = fields.DataMountPath
+	restored.Spec.Storage = fields.Storage
+	// The resources of the pod policy may have been changed in v1beta1.
+	restored.Spec.Pod = fields.Pod
+	if restored.Spec.Pod == nil && out.Spec.Pod != nil {
+		restored.Spec.Pod = &v1alpha1.JiraPodPolicy{}
+	}
+	if restored.Spec.Pod != nil {
+		restored.Spec.Pod.Resources = v1.ResourceRequirements{}
+		if out.Spec.Pod != nil {
+			restored.Spec.Pod.Resources = out.Spec.Pod.Resources
+		}
+	}
+	converted, err := convertJiraFromV1alpha1(restored)
+	if err != nil || !equality.Semantic.DeepEqual(converted.Spec, in.Spec) {
+		return out, nil
+	}
+	return restored, nil
+}
+
+// convertJiraToV1alpha1 converts the fields of a Jira resource to v1alpha1.
+func convertJiraToV1alpha1(in *Jira) (*v1alpha1.Jira, error) {
+	out := &v1alpha1.Jira{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	removeAnnotation(&out.ObjectMeta.Annotations)
+	if err := convertJSON(in.Status, &out.Status); err != nil {
+		return nil, err
+	}
+
+	spec := &out.Spec
+	spec.BaseImage = in.Spec.Image.Repository
+	spec.BaseImageVersion = in.Spec.Image.Tag
+	if in.Spec.Replicas != nil {
+		replicas := *in.Spec.Replicas
+		spec.Replicas = &replicas
+	}
+	spec.ConfigMapName = in.Spec.ConfigMapName
+	spec.SecretName = in.Spec.SecretName
+	if !isEmptyResources(in.Spec.Resources) {
+		spec.Pod = &v1alpha1.JiraPodPolicy{Resources: *in.Spec.Resources.DeepCopy()}
+	}
+	for _, f := range []struct{ in, out interface{} }{
+		{in.Spec.Cluster, &spec.Cluster},
+		{in.Spec.Upgrade, &spec.Upgrade},
+		{in.Spec.Expose, &spec.Expose},
+		{in.Spec.Probes, &spec.Probes},
+		{in.Spec.Storage, &spec.Storage},
+		{in.Spec.Backup, &spec.Backup},
+		{in.Spec.Monitoring, &spec.Monitoring},
+	} {
+		if err := convertJSON(f.in, f.out); err != nil {
+			return nil, err
+		}
+	}
+
+	// A local home that only sets the mount path is the data mount path.
+	if s := spec.Storage; s != nil && s.LocalHome != nil {
+		spec.DataMountPath = s.LocalHome.MountPath
+		if s.LocalHome.PersistentVolumeClaimSpec == nil && s.LocalHome.EmptyDir == nil {
+			s.LocalHome = nil
+		}
+		if reflect.DeepEqual(*s, v1alpha1.JiraStorageSpec{}) {
+			spec.Storage = nil
+		}
+	}
+
+	if db := in.Spec.Database; db != nil {
+		spec.Database = &v1alpha1.JiraDatabaseSpec{
+			Type:              v1alpha1.DatabaseType(db.Type),
+			Host:              db.Host,
+			Port:              db.Port,
+			Name:              db.Name,
+			Schema:            db.Schema,
+			CredentialsSecret: db.CredentialsSecret,
+		}
+		if db.Pool != nil {
+			spec.Database.PoolMinSize = db.Pool.MinSize
+			spec.Database.PoolMaxSize = db.Pool.MaxSize
+			spec.Database.ValidationQuery = db.Pool.ValidationQuery
+		}
+	}
+	return out, nil
+}
+
+// ConvertJiraRestoreFromV1alpha1 converts a v1alpha1 JiraRestore resource,
+// which has the same fields in both versions.
+func ConvertJiraRestoreFromV1alpha1(in *v1alpha1.JiraRestore) (*JiraRestore, error) {
+	out := &JiraRestore{}
+	if err := convertJSON(in, out); err != nil {
+		return nil, err
+	}
+	out.APIVersion = SchemeGroupVersion.String()
+	return out, nil
+}
+
+// ConvertJiraRestoreToV1alpha1 converts a JiraRestore resource to v1alpha1.
+func ConvertJiraRestoreToV1alpha1(in *JiraRestore) (*v1alpha1.JiraRestore, error) {
+	out := &v1alpha1.JiraRestore{}
+	if err := convertJSON(in, out); err != nil {
+		return nil, err
+	}
+	out.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	return out, nil
+}
+
+// convertJSON converts between types that serialize to the same JSON.
+func convertJSON(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// isEmptyResources returns true if no resources are requested or limited.
+func isEmptyResources(r v1.ResourceRequirements) bool {
+	return len(r.Limits) == 0 && len(r.Requests) == 0
+}
+
+// removeAnnotation removes the v1alpha1 spec annotation from the annotations.
+func removeAnnotation(annotations *map[string]string) {
+	delete(*annotations, v1alpha1SpecAnnotation)
+	if len(*annotations) == 0 {
+		*annotations = nil
+	}
+}
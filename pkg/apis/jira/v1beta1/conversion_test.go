@@ -0,0 +1,243 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func claimSpec(size string) *v1.PersistentVolumeClaimSpec {
+	return &v1.PersistentVolumeClaimSpec{
+		AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+		},
+	}
+}
+
+func cpu(q string) v1.ResourceRequirements {
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(q)},
+	}
+}
+
+func newJira(spec v1alpha1.JiraSpec) *v1alpha1.Jira {
+	spec.BaseImage = "cptactionhank/atlassian-jira-software"
+	spec.BaseImageVersion = "7.12.1"
+	return &v1alpha1.Jira{
+		TypeMeta:   metav1.TypeMeta{Kind: "Jira", APIVersion: v1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "jira", Namespace: "default"},
+		Spec:       spec,
+	}
+}
+
+func TestConvertJiraRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		spec v1alpha1.JiraSpec
+		// annotated is whether the v1alpha1 fields are kept in the annotation.
+		annotated bool
+	}{
+		{
+			name: "minimal",
+		},
+		{
+			name: "data mount path",
+			spec: v1alpha1.JiraSpec{DataMountPath: "/var/atlassian/jira"},
+		},
+		{
+			name: "data mount path and local home",
+			spec: v1alpha1.JiraSpec{
+				DataMountPath: "/var/atlassian/jira",
+				Storage: &v1alpha1.JiraStorageSpec{
+					LocalHome: &v1alpha1.JiraVolumeSpec{MountPath: "/data"},
+				},
+			},
+			annotated: true,
+		},
+		{
+			name: "pod resources",
+			spec: v1alpha1.JiraSpec{Pod: &v1alpha1.JiraPodPolicy{Resources: cpu("1")}},
+		},
+		{
+			name: "pod claim without local home",
+			spec: v1alpha1.JiraSpec{
+				DataMountPath: "/var/atlassian/jira",
+				Pod: &v1alpha1.JiraPodPolicy{
+					Resources:                 cpu("1"),
+					PersistentVolumeClaimSpec: claimSpec("10Gi"),
+				},
+			},
+			annotated: true,
+		},
+		{
+			name: "pod claim with local home",
+			spec: v1alpha1.JiraSpec{
+				DataMountPath: "/var/atlassian/jira",
+				Pod: &v1alpha1.JiraPodPolicy{
+					PersistentVolumeClaimSpec: claimSpec("10Gi"),
+				},
+				Storage: &v1alpha1.JiraStorageSpec{
+					LocalHome: &v1alpha1.JiraVolumeSpec{PersistentVolumeClaimSpec: claimSpec("20Gi")},
+				},
+			},
+			annotated: true,
+		},
+		{
+			name: "local home claim",
+			spec: v1alpha1.JiraSpec{
+				DataMountPath: "/var/atlassian/jira",
+				Storage: &v1alpha1.JiraStorageSpec{
+					LocalHome: &v1alpha1.JiraVolumeSpec{PersistentVolumeClaimSpec: claimSpec("20Gi")},
+				},
+			},
+			annotated: true,
+		},
+		{
+			name: "database pool",
+			spec: v1alpha1.JiraSpec{
+				Database: &v1alpha1.JiraDatabaseSpec{
+					Type:            v1alpha1.DatabaseTypePostgres,
+					Host:            "postgres",
+					Port:            5432,
+					Name:            "jira",
+					PoolMinSize:     5,
+					PoolMaxSize:     40,
+					ValidationQuery: "select 1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		in := newJira(tt.spec)
+		out, err := ConvertJiraFromV1alpha1(in)
+		if err != nil {
+			t.Fatalf("%s: ConvertJiraFromV1alpha1: %v", tt.name, err)
+		}
+		if _, ok := out.Annotations[v1alpha1SpecAnnotation]; ok != tt.annotated {
+			t.Errorf("%s: annotation set = %v, want %v", tt.name, ok, tt.annotated)
+		}
+		back, err := ConvertJiraToV1alpha1(out)
+		if err != nil {
+			t.Fatalf("%s: ConvertJiraToV1alpha1: %v", tt.name, err)
+		}
+		if !equality.Semantic.DeepEqual(back.Spec, in.Spec) {
+			t.Errorf("%s: round trip changed the spec\n got: %+v\nwant: %+v", tt.name, back.Spec, in.Spec)
+		}
+		if _, ok := back.Annotations[v1alpha1SpecAnnotation]; ok {
+			t.Errorf("%s: annotation kept in v1alpha1", tt.name)
+		}
+	}
+}
+
+func TestConvertJiraDatabasePool(t *testing.T) {
+	in := newJira(v1alpha1.JiraSpec{
+		Database: &v1alpha1.JiraDatabaseSpec{
+			Type:        v1alpha1.DatabaseTypeMySQL,
+			PoolMinSize: 5,
+			PoolMaxSize: 40,
+		},
+	})
+	out, err := ConvertJiraFromV1alpha1(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &JiraDatabasePoolSpec{MinSize: 5, MaxSize: 40}
+	if db := out.Spec.Database; db == nil || !equality.Semantic.DeepEqual(db.Pool, want) {
+		t.Errorf("database = %+v, want pool %+v", out.Spec.Database, want)
+	}
+}
+
+func TestConvertJiraResourcesChangedInV1beta1(t *testing.T) {
+	tests := []struct {
+		name      string
+		pod       *v1alpha1.JiraPodPolicy
+		resources v1.ResourceRequirements
+		want      *v1alpha1.JiraPodPolicy
+	}{
+		{
+			name:      "changed with pod claim",
+			pod:       &v1alpha1.JiraPodPolicy{Resources: cpu("1"), PersistentVolumeClaimSpec: claimSpec("10Gi")},
+			resources: cpu("2"),
+			want:      &v1alpha1.JiraPodPolicy{Resources: cpu("2"), PersistentVolumeClaimSpec: claimSpec("10Gi")},
+		},
+		{
+			name:      "removed with pod claim",
+			pod:       &v1alpha1.JiraPodPolicy{Resources: cpu("1"), PersistentVolumeClaimSpec: claimSpec("10Gi")},
+			resources: v1.ResourceRequirements{},
+			want:      &v1alpha1.JiraPodPolicy{PersistentVolumeClaimSpec: claimSpec("10Gi")},
+		},
+		{
+			name:      "added with pod claim",
+			pod:       &v1alpha1.JiraPodPolicy{PersistentVolumeClaimSpec: claimSpec("10Gi")},
+			resources: cpu("500m"),
+			want:      &v1alpha1.JiraPodPolicy{Resources: cpu("500m"), PersistentVolumeClaimSpec: claimSpec("10Gi")},
+		},
+		{
+			name:      "changed without pod claim",
+			pod:       &v1alpha1.JiraPodPolicy{Resources: cpu("1")},
+			resources: cpu("2"),
+			want:      &v1alpha1.JiraPodPolicy{Resources: cpu("2")},
+		},
+	}
+	for _, tt := range tests {
+		in := newJira(v1alpha1.JiraSpec{DataMountPath: "/var/atlassian/jira", Pod: tt.pod})
+		out, err := ConvertJiraFromV1alpha1(in)
+		if err != nil {
+			t.Fatalf("%s: ConvertJiraFromV1alpha1: %v", tt.name, err)
+		}
+		out.Spec.Resources = tt.resources
+		back, err := ConvertJiraToV1alpha1(out)
+		if err != nil {
+			t.Fatalf("%s: ConvertJiraToV1alpha1: %v", tt.name, err)
+		}
+		if !equality.Semantic.DeepEqual(back.Spec.Pod, tt.want) {
+			t.Errorf("%s: pod = %+v, want %+v", tt.name, back.Spec.Pod, tt.want)
+		}
+		if back.Spec.DataMountPath != in.Spec.DataMountPath {
+			t.Errorf("%s: data_mount_path = %q, want %q", tt.name, back.Spec.DataMountPath, in.Spec.DataMountPath)
+		}
+	}
+}
+
+func TestConvertJiraStaleAnnotation(t *testing.T) {
+	in := newJira(v1alpha1.JiraSpec{
+		DataMountPath: "/var/atlassian/jira",
+		Pod:           &v1alpha1.JiraPodPolicy{PersistentVolumeClaimSpec: claimSpec("10Gi")},
+	})
+	out, err := ConvertJiraFromV1alpha1(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A change of the local home in v1beta1 invalidates the annotation.
+	out.Spec.Storage.LocalHome.MountPath = "/data"
+	back, err := ConvertJiraToV1alpha1(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Spec.DataMountPath != "/data" {
+		t.Errorf("data_mount_path = %q, want /data", back.Spec.DataMountPath)
+	}
+	if back.Spec.Pod != nil && back.Spec.Pod.PersistentVolumeClaimSpec != nil {
+		t.Errorf("pod claim restored from a stale annotation: %+v", back.Spec.Pod)
+	}
+}
@@ -0,0 +1,636 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// JiraList resource
+type JiraList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Jira `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Jira resource
+type Jira struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              JiraSpec   `json:"spec"`
+	Status            JiraStatus `json:"status,omitempty"`
+}
+
+// JiraSpec defines the desired state of a JIRA instance.
+type JiraSpec struct {
+	// Image is the JIRA image.
+	Image JiraImageSpec `json:"image,omitempty"`
+
+	// Replicas is the number of JIRA nodes. More than one node requires JIRA
+	// Data Center, an external database and a shared home. Defaults to 1.
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// ConfigMapName is the name of ConfigMap to use or create.
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// SecretName is the name of the Secret to use. The Secret holds the
+	// database credentials (username, password) and optionally the JIRA
+	// license (license) and the credentials of a JIRA administrator
+	// (admin-username, admin-password), which are mounted as files into the
+	// JIRA container.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Resources is the resource requirements of the JIRA container. Changes
+	// are rolled out to the pods.
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Cluster defines the JIRA Data Center cluster settings. It is only used
+	// if a shared home is defined.
+	Cluster *JiraClusterSpec `json:"cluster,omitempty"`
+
+	// Upgrade defines how changes to the image tag are rolled out.
+	Upgrade *JiraUpgradeSpec `json:"upgrade,omitempty"`
+
+	// Database defines the database used by JIRA. If no database is defined,
+	// the embedded H2 database is used.
+	Database *JiraDatabaseSpec `json:"database,omitempty"`
+
+	// Expose defines how JIRA is exposed outside of the cluster.
+	Expose *JiraExposeSpec `json:"expose,omitempty"`
+
+	// Probes defines the readiness and liveness probes of the JIRA container.
+	Probes *JiraProbeSpec `json:"probes,omitempty"`
+
+	// Storage defines the data volumes of JIRA.
+	Storage *JiraStorageSpec `json:"storage,omitempty"`
+
+	// Backup defines scheduled backups of JIRA Home and the database.
+	Backup *JiraBackupSpec `json:"backup,omitempty"`
+
+	// Monitoring defines the export of JIRA application metrics to
+	// Prometheus.
+	Monitoring *JiraMonitoringSpec `json:"monitoring,omitempty"`
+}
+
+// JiraImageSpec defines the JIRA image.
+type JiraImageSpec struct {
+	// Repository is the image without tag, e.g. cptactionhank/atlassian-jira.
+	Repository string `json:"repository,omitempty"`
+
+	// Tag is the image tag, which is the JIRA version.
+	Tag string `json:"tag,omitempty"`
+}
+
+// StorageReclaimPolicy defines what happens to the JIRA Home PVCs when the
+// Jira resource is deleted.
+type StorageReclaimPolicy string
+
+const (
+	// StorageReclaimRetain keeps the PVCs.
+	StorageReclaimRetain StorageReclaimPolicy = "Retain"
+	// StorageReclaimDelete deletes the PVCs.
+	StorageReclaimDelete StorageReclaimPolicy = "Delete"
+	// StorageReclaimSnapshot takes a VolumeSnapshot of each PVC and deletes
+	// the PVCs once the snapshots are ready. If a snapshot fails the deletion
+	// is held back with the ReclaimFailed condition until the policy is
+	// changed to Retain or Delete.
+	StorageReclaimSnapshot StorageReclaimPolicy = "Snapshot"
+)
+
+// JiraStorageSpec defines the data volumes of JIRA.
+type JiraStorageSpec struct {
+	// ReclaimPolicy defines what happens to the PVCs when the Jira resource
+	// is deleted. One of Retain, Delete or Snapshot. Defaults to Retain.
+	ReclaimPolicy StorageReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// SnapshotClassName is the VolumeSnapshotClass used by the Snapshot
+	// reclaim policy and snapshot backups.
+	SnapshotClassName string `json:"snapshotClassName,omitempty"`
+
+	// DataSource is the VolumeSnapshot a new JIRA Home PVC is created from.
+	DataSource *JiraDataSource `json:"dataSource,omitempty"`
+
+	// LocalHome is the JIRA Home of each node. Without a PVC spec it is an
+	// emptyDir mounted at /var/atlassian/jira.
+	LocalHome *JiraVolumeSpec `json:"localHome,omitempty"`
+
+	// SharedHome is the home directory shared by the nodes of a JIRA Data
+	// Center cluster. Defining a shared home enables clustering.
+	SharedHome *JiraVolumeSpec `json:"sharedHome,omitempty"`
+}
+
+// JiraVolumeSpec defines a volume of JIRA. At most one of
+// PersistentVolumeClaimSpec and EmptyDir may be set, an emptyDir is used if
+// neither is set.
+type JiraVolumeSpec struct {
+	// PersistentVolumeClaimSpec is the spec of the PVC of the volume.
+	PersistentVolumeClaimSpec *v1.PersistentVolumeClaimSpec `json:"persistentVolumeClaimSpec,omitempty"`
+
+	// EmptyDir is the emptyDir of the volume.
+	EmptyDir *v1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// MountPath is the path the volume is mounted at.
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// JiraDataSource defines the source of a new JIRA Home PVC.
+type JiraDataSource struct {
+	// VolumeSnapshotName is the name of a VolumeSnapshot in the namespace of
+	// the Jira resource.
+	VolumeSnapshotName string `json:"volumeSnapshotName"`
+}
+
+// JiraClusterSpec defines the JIRA Data Center cluster settings.
+type JiraClusterSpec struct {
+	// EhcacheListenerPort is the port of the Ehcache RMI listener.
+	EhcacheListenerPort int32 `json:"ehcacheListenerPort,omitempty"`
+
+	// EhcacheObjectPort is the port of the Ehcache remote objects.
+	EhcacheObjectPort int32 `json:"ehcacheObjectPort,omitempty"`
+
+	// DrainSeconds is the time a node is removed from the Service before it
+	// is restarted during a rolling restart.
+	DrainSeconds int32 `json:"drainSeconds,omitempty"`
+}
+
+// JiraUpgradeSpec defines the policy for JIRA version upgrades.
+type JiraUpgradeSpec struct {
+	// Backup enables a backup of JIRA Home before the new version is rolled
+	// out. This field is ignored if the local home is not persistent.
+	Backup bool `json:"backup,omitempty"`
+}
+
+// DatabaseType is the type of database used by JIRA.
+type DatabaseType string
+
+const (
+	// DatabaseTypeH2 is the embedded H2 database.
+	DatabaseTypeH2 DatabaseType = "h2"
+	// DatabaseTypePostgres is a PostgreSQL database.
+	DatabaseTypePostgres DatabaseType = "postgres"
+	// DatabaseTypeMySQL is a MySQL database.
+	DatabaseTypeMySQL DatabaseType = "mysql"
+	// DatabaseTypeMSSQL is a Microsoft SQL Server database.
+	DatabaseTypeMSSQL DatabaseType = "mssql"
+	// DatabaseTypeOracle is an Oracle database.
+	DatabaseTypeOracle DatabaseType = "oracle"
+)
+
+// JiraDatabaseSpec defines the database used by JIRA.
+type JiraDatabaseSpec struct {
+	// Type is the type of database, one of h2, postgres, mysql, mssql or oracle.
+	Type DatabaseType `json:"type"`
+
+	// Host is the hostname of the database server.
+	Host string `json:"host,omitempty"`
+
+	// Port is the port of the database server.
+	Port int32 `json:"port,omitempty"`
+
+	// Name is the name of the database, or the service name for Oracle.
+	Name string `json:"name,omitempty"`
+
+	// Schema is the database schema used by JIRA.
+	Schema string `json:"schema,omitempty"`
+
+	// CredentialsSecret is the name of the Secret holding the database
+	// credentials in the username and password keys. Defaults to SecretName.
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// Pool defines the connection pool.
+	Pool *JiraDatabasePoolSpec `json:"pool,omitempty"`
+}
+
+// JiraDatabasePoolSpec defines the database connection pool.
+type JiraDatabasePoolSpec struct {
+	// MinSize is the minimum size of the connection pool.
+	MinSize int32 `json:"minSize,omitempty"`
+
+	// MaxSize is the maximum size of the connection pool.
+	MaxSize int32 `json:"maxSize,omitempty"`
+
+	// ValidationQuery is the query used to validate pooled connections.
+	ValidationQuery string `json:"validationQuery,omitempty"`
+}
+
+// JiraExposeSpec defines how JIRA is exposed.
+type JiraExposeSpec struct {
+	// ServiceType is the type of the JIRA Service, one of ClusterIP, NodePort
+	// or LoadBalancer. Defaults to NodePort.
+	ServiceType v1.ServiceType `json:"serviceType,omitempty"`
+
+	// Ingress defines an Ingress managed by the operator.
+	Ingress *JiraIngressSpec `json:"ingress,omitempty"`
+
+	// Route defines an OpenShift Route managed by the operator.
+	Route *JiraRouteSpec `json:"route,omitempty"`
+
+	// ContextPath is the path JIRA is served from, e.g. /jira.
+	ContextPath string `json:"contextPath,omitempty"`
+
+	// Proxy overrides the reverse proxy settings derived from the Ingress or
+	// Route.
+	Proxy *JiraProxySpec `json:"proxy,omitempty"`
+}
+
+// JiraProxySpec defines the reverse proxy settings of the JIRA connector.
+type JiraProxySpec struct {
+	// Name is the host name clients use to reach JIRA.
+	Name string `json:"name,omitempty"`
+
+	// Port is the port clients use to reach JIRA.
+	Port int32 `json:"port,omitempty"`
+
+	// Scheme is the scheme clients use to reach JIRA, http or https.
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// JiraIngressSpec defines the Ingress for JIRA.
+type JiraIngressSpec struct {
+	// Host is the fully qualified domain name of JIRA.
+	Host string `json:"host"`
+
+	// Path is the path JIRA is served from. Defaults to the context path or /.
+	Path string `json:"path,omitempty"`
+
+	// IngressClass is the value of the kubernetes.io/ingress.class annotation.
+	IngressClass string `json:"ingressClass,omitempty"`
+
+	// Annotations are additional annotations for the Ingress.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// TLSSecretName is the name of the Secret with the TLS certificate. TLS is
+	// enabled if this field is set.
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// JiraRouteSpec defines the OpenShift Route for JIRA.
+type JiraRouteSpec struct {
+	// Host is the fully qualified domain name of JIRA. If empty, the router
+	// generates a host name.
+	Host string `json:"host,omitempty"`
+
+	// Path is the path JIRA is served from.
+	Path string `json:"path,omitempty"`
+
+	// TLSTermination is the TLS termination type, one of edge, passthrough
+	// or reencrypt. TLS is enabled if this field is set.
+	TLSTermination string `json:"tlsTermination,omitempty"`
+}
+
+// JiraProbeSpec defines the probes against the JIRA status endpoint.
+type JiraProbeSpec struct {
+	// StartupSeconds is the time JIRA is given to start before the liveness
+	// probe is started.
+	StartupSeconds int32 `json:"startupSeconds,omitempty"`
+
+	// PeriodSeconds is the interval between probes.
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// TimeoutSeconds is the timeout of a single probe.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// ReadinessFailureThreshold is the number of failed probes before JIRA is
+	// removed from the Service.
+	ReadinessFailureThreshold int32 `json:"readinessFailureThreshold,omitempty"`
+
+	// LivenessFailureThreshold is the number of failed probes before JIRA is
+	// restarted.
+	LivenessFailureThreshold int32 `json:"livenessFailureThreshold,omitempty"`
+}
+
+// JiraBackupMode is the way backups are taken.
+type JiraBackupMode string
+
+const (
+	// JiraBackupModeArchive archives JIRA Home and dumps the database to
+	// S3-compatible object storage.
+	JiraBackupModeArchive JiraBackupMode = "Archive"
+	// JiraBackupModeSnapshot stops JIRA and takes a VolumeSnapshot of the
+	// JIRA Home PVC. It is not supported for a cluster, as the snapshot does
+	// not cover the shared home.
+	JiraBackupModeSnapshot JiraBackupMode = "Snapshot"
+)
+
+// JiraBackupSpec defines scheduled backups of JIRA Home and the database.
+type JiraBackupSpec struct {
+	// Mode is the way backups are taken. One of Archive or Snapshot.
+	// Defaults to Archive.
+	Mode JiraBackupMode `json:"mode,omitempty"`
+
+	// Schedule is the cron schedule of the backups in the syntax of a
+	// CronJob schedule.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Suspend stops the scheduling of new backups.
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Retention is the number of backups or snapshots kept.
+	Retention int32 `json:"retention,omitempty"`
+
+	// Image is the image of the MinIO client used to upload the backups.
+	Image string `json:"image,omitempty"`
+
+	// DumpImage is the image used to dump the database.
+	DumpImage string `json:"dumpImage,omitempty"`
+
+	// S3 is the object storage the backups are uploaded to. Required by the
+	// Archive mode.
+	S3 JiraBackupS3Spec `json:"s3,omitempty"`
+}
+
+// JiraBackupS3Spec defines the S3-compatible object storage of backups.
+type JiraBackupS3Spec struct {
+	// Endpoint is the URL of the S3 endpoint.
+	Endpoint string `json:"endpoint"`
+
+	// Bucket is the name of the bucket the backups are uploaded to.
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to the backup paths in the bucket. Defaults to
+	// <namespace>/<name>.
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecret is the name of the Secret with the access key
+	// (access-key-id) and secret key (secret-access-key).
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// JiraMonitoringMode is the way the JMX exporter is run.
+type JiraMonitoringMode string
+
+const (
+	// JiraMonitoringModeAgent runs the JMX exporter as a Java agent in the
+	// JVM of JIRA.
+	JiraMonitoringModeAgent JiraMonitoringMode = "Agent"
+	// JiraMonitoringModeSidecar runs the JMX exporter in a sidecar container
+	// that connects to the JMX remote connector of JIRA.
+	JiraMonitoringModeSidecar JiraMonitoringMode = "Sidecar"
+)
+
+// JiraMonitoringSpec defines a JMX exporter that serves the JVM, Tomcat and
+// JIRA metrics in the Prometheus format.
+type JiraMonitoringSpec struct {
+	// Mode is the way the JMX exporter is run, either Agent or Sidecar.
+	// Defaults to Agent.
+	Mode JiraMonitoringMode `json:"mode,omitempty"`
+
+	// Port is the port the metrics are served on.
+	Port int32 `json:"port,omitempty"`
+
+	// JMXPort is the port of the JMX remote connector of JIRA, which is only
+	// enabled in the Sidecar mode.
+	JMXPort int32 `json:"jmxPort,omitempty"`
+
+	// AgentURL is the download URL of the JMX exporter Java agent used in the
+	// Agent mode.
+	AgentURL string `json:"agentURL,omitempty"`
+
+	// Image is the image of the JMX exporter sidecar used in the Sidecar
+	// mode.
+	Image string `json:"image,omitempty"`
+
+	// Config is the configuration of the JMX exporter in YAML.
+	Config string `json:"config,omitempty"`
+
+	// ServiceMonitor creates a ServiceMonitor for the Prometheus Operator
+	// that scrapes the JMX exporter.
+	ServiceMonitor *JiraServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+}
+
+// JiraServiceMonitorSpec defines the ServiceMonitor of the JMX exporter.
+type JiraServiceMonitorSpec struct {
+	// Interval is the scrape interval, e.g. 30s.
+	Interval string `json:"interval,omitempty"`
+
+	// Labels are added to the ServiceMonitor.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// JiraPhase is the lifecycle phase of a JIRA instance.
+type JiraPhase string
+
+// JiraConditionType is the type of a JIRA condition.
+type JiraConditionType string
+
+// JiraCondition describes the state of a JIRA instance at a certain point.
+type JiraCondition struct {
+	// Type of the condition.
+	Type JiraConditionType `json:"type"`
+
+	// Status of the condition, one of True, False or Unknown.
+	Status v1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime is the last time the condition changed status.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a one-word CamelCase reason for the last transition.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the last transition.
+	Message string `json:"message,omitempty"`
+}
+
+// JiraStatus is the observed state of a JIRA instance.
+type JiraStatus struct {
+	// Phase is the current lifecycle phase of the JIRA instance.
+	Phase JiraPhase `json:"phase,omitempty"`
+
+	// Conditions are the latest observations of the JIRA resources.
+	Conditions []JiraCondition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation last processed by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Endpoint is the in-cluster URL of the JIRA service.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// URL is the external URL of JIRA if it is exposed by an Ingress or Route.
+	URL string `json:"url,omitempty"`
+
+	// Image is the image currently running in the JIRA Pod.
+	Image string `json:"image,omitempty"`
+
+	// Version is the JIRA version currently deployed.
+	Version string `json:"version,omitempty"`
+
+	// Replicas is the number of JIRA nodes.
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of ready JIRA nodes.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Selector is the label selector of the JIRA pods.
+	Selector string `json:"selector,omitempty"`
+
+	// Upgrade is the state of the current or last version upgrade.
+	Upgrade *JiraUpgradeStatus `json:"upgrade,omitempty"`
+
+	// Backup is the state of the scheduled backups.
+	Backup *JiraBackupStatus `json:"backup,omitempty"`
+
+	// Rollout is the state of the rolling restart of the cluster nodes in
+	// progress.
+	Rollout *JiraRolloutStatus `json:"rollout,omitempty"`
+}
+
+// JiraUpgradePhase is the phase of a JIRA version upgrade.
+type JiraUpgradePhase string
+
+// JiraUpgradeStatus is the state of a JIRA version upgrade.
+type JiraUpgradeStatus struct {
+	// FromVersion is the version that was running when the upgrade started.
+	FromVersion string `json:"fromVersion"`
+
+	// ToVersion is the version being upgraded to.
+	ToVersion string `json:"toVersion"`
+
+	// Phase is the current phase of the upgrade.
+	Phase JiraUpgradePhase `json:"phase"`
+
+	// Reason is a one-word CamelCase reason for a failed upgrade.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the upgrade outcome.
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the upgrade was started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// PhaseStartTime is the time the current phase of the upgrade started.
+	PhaseStartTime *metav1.Time `json:"phaseStartTime,omitempty"`
+
+	// CompletionTime is the time the upgrade completed or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// JiraBackupStatus is the state of the scheduled backups.
+type JiraBackupStatus struct {
+	// LastScheduleTime is the time the last backup was started.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastSuccessTime is the time the last successful backup completed.
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// LastFailureTime is the time the last backup failed.
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// Snapshot is the snapshot backup in progress.
+	Snapshot *JiraSnapshotBackupStatus `json:"snapshot,omitempty"`
+}
+
+// JiraSnapshotBackupPhase is the phase of a snapshot backup.
+type JiraSnapshotBackupPhase string
+
+// JiraSnapshotBackupStatus is the state of a snapshot backup in progress.
+type JiraSnapshotBackupStatus struct {
+	// Name is the name of the VolumeSnapshot.
+	Name string `json:"name"`
+
+	// Phase is the current phase of the snapshot backup.
+	Phase JiraSnapshotBackupPhase `json:"phase"`
+
+	// StartTime is the time the snapshot backup was started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// JiraRolloutPhase is the phase of the node being restarted by a rolling
+// restart.
+type JiraRolloutPhase string
+
+// JiraRolloutStatus is the state of a rolling restart of the cluster nodes.
+type JiraRolloutStatus struct {
+	// Revision is the hash of the pod template rolled out.
+	Revision string `json:"revision"`
+
+	// Partition is the ordinal from which on nodes run the new revision.
+	Partition int32 `json:"partition"`
+
+	// Node is the name of the node currently restarted.
+	Node string `json:"node,omitempty"`
+
+	// Phase is the phase of the node currently restarted.
+	Phase JiraRolloutPhase `json:"phase,omitempty"`
+
+	// PhaseStartTime is the time the current phase started.
+	PhaseStartTime metav1.Time `json:"phaseStartTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// JiraRestoreList resource
+type JiraRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []JiraRestore `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// JiraRestore resource restores a Jira resource from a backup.
+type JiraRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              JiraRestoreSpec   `json:"spec"`
+	Status            JiraRestoreStatus `json:"status,omitempty"`
+}
+
+// JiraRestoreSpec defines the backup to restore and the Jira resource it is
+// restored to.
+type JiraRestoreSpec struct {
+	// JiraName is the name of the Jira resource in the same namespace that is
+	// restored.
+	JiraName string `json:"jiraName"`
+
+	// Backup is the name of the backup to restore, i.e. the time stamped
+	// directory below the backup prefix in the bucket.
+	Backup string `json:"backup"`
+
+	// S3 is the object storage the backup is downloaded from. Defaults to the
+	// backup storage of the Jira resource.
+	S3 *JiraBackupS3Spec `json:"s3,omitempty"`
+
+	// Image is the image of the MinIO client used to download the backup.
+	Image string `json:"image,omitempty"`
+
+	// RestoreImage is the image used to restore the database.
+	RestoreImage string `json:"restoreImage,omitempty"`
+}
+
+// JiraRestorePhase is the phase of a restore.
+type JiraRestorePhase string
+
+// JiraRestoreStatus is the observed state of a restore.
+type JiraRestoreStatus struct {
+	// Phase is the current phase of the restore.
+	Phase JiraRestorePhase `json:"phase,omitempty"`
+
+	// Reason is a machine readable reason for a failed restore.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the restore state.
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the restore was started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the restore completed or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
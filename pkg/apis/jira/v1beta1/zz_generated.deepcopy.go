@@ -0,0 +1,882 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !ignore_autogenerated
+
+// This file was autogenerated by deepcopy-gen. Do not edit it manually!
+
+package v1beta1
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Jira) DeepCopyInto(out *Jira) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Jira.
+func (in *Jira) DeepCopy() *Jira {
+	if in == nil {
+		return nil
+	}
+	out := new(Jira)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Jira) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraBackupS3Spec) DeepCopyInto(out *JiraBackupS3Spec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraBackupS3Spec.
+func (in *JiraBackupS3Spec) DeepCopy() *JiraBackupS3Spec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraBackupS3Spec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraBackupSpec) DeepCopyInto(out *JiraBackupSpec) {
+	*out = *in
+	out.S3 = in.S3
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraBackupSpec.
+func (in *JiraBackupSpec) DeepCopy() *JiraBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraBackupStatus) DeepCopyInto(out *JiraBackupStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.LastSuccessTime != nil {
+		in, out := &in.LastSuccessTime, &out.LastSuccessTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Snapshot != nil {
+		in, out := &in.Snapshot, &out.Snapshot
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraSnapshotBackupStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraBackupStatus.
+func (in *JiraBackupStatus) DeepCopy() *JiraBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraClusterSpec) DeepCopyInto(out *JiraClusterSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraClusterSpec.
+func (in *JiraClusterSpec) DeepCopy() *JiraClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraCondition) DeepCopyInto(out *JiraCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraCondition.
+func (in *JiraCondition) DeepCopy() *JiraCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraDataSource) DeepCopyInto(out *JiraDataSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraDataSource.
+func (in *JiraDataSource) DeepCopy() *JiraDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraDatabasePoolSpec) DeepCopyInto(out *JiraDatabasePoolSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraDatabasePoolSpec.
+func (in *JiraDatabasePoolSpec) DeepCopy() *JiraDatabasePoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraDatabasePoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraDatabaseSpec) DeepCopyInto(out *JiraDatabaseSpec) {
+	*out = *in
+	if in.Pool != nil {
+		in, out := &in.Pool, &out.Pool
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraDatabasePoolSpec)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraDatabaseSpec.
+func (in *JiraDatabaseSpec) DeepCopy() *JiraDatabaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraDatabaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraExposeSpec) DeepCopyInto(out *JiraExposeSpec) {
+	*out = *in
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraIngressSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Route != nil {
+		in, out := &in.Route, &out.Route
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraRouteSpec)
+			**out = **in
+		}
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraProxySpec)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraExposeSpec.
+func (in *JiraExposeSpec) DeepCopy() *JiraExposeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraExposeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraImageSpec) DeepCopyInto(out *JiraImageSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraImageSpec.
+func (in *JiraImageSpec) DeepCopy() *JiraImageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraImageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraIngressSpec) DeepCopyInto(out *JiraIngressSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraIngressSpec.
+func (in *JiraIngressSpec) DeepCopy() *JiraIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraList) DeepCopyInto(out *JiraList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Jira, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraList.
+func (in *JiraList) DeepCopy() *JiraList {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JiraList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraMonitoringSpec) DeepCopyInto(out *JiraMonitoringSpec) {
+	*out = *in
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraServiceMonitorSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraMonitoringSpec.
+func (in *JiraMonitoringSpec) DeepCopy() *JiraMonitoringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraMonitoringSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraProbeSpec) DeepCopyInto(out *JiraProbeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraProbeSpec.
+func (in *JiraProbeSpec) DeepCopy() *JiraProbeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraProbeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraProxySpec) DeepCopyInto(out *JiraProxySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraProxySpec.
+func (in *JiraProxySpec) DeepCopy() *JiraProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraRestore) DeepCopyInto(out *JiraRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraRestore.
+func (in *JiraRestore) DeepCopy() *JiraRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JiraRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraRestoreList) DeepCopyInto(out *JiraRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JiraRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraRestoreList.
+func (in *JiraRestoreList) DeepCopy() *JiraRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JiraRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraRestoreSpec) DeepCopyInto(out *JiraRestoreSpec) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraBackupS3Spec)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraRestoreSpec.
+func (in *JiraRestoreSpec) DeepCopy() *JiraRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraRestoreStatus) DeepCopyInto(out *JiraRestoreStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraRestoreStatus.
+func (in *JiraRestoreStatus) DeepCopy() *JiraRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraRolloutStatus) DeepCopyInto(out *JiraRolloutStatus) {
+	*out = *in
+	in.PhaseStartTime.DeepCopyInto(&out.PhaseStartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraRolloutStatus.
+func (in *JiraRolloutStatus) DeepCopy() *JiraRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraRouteSpec) DeepCopyInto(out *JiraRouteSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraRouteSpec.
+func (in *JiraRouteSpec) DeepCopy() *JiraRouteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraRouteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraServiceMonitorSpec) DeepCopyInto(out *JiraServiceMonitorSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraServiceMonitorSpec.
+func (in *JiraServiceMonitorSpec) DeepCopy() *JiraServiceMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraServiceMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraSnapshotBackupStatus) DeepCopyInto(out *JiraSnapshotBackupStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraSnapshotBackupStatus.
+func (in *JiraSnapshotBackupStatus) DeepCopy() *JiraSnapshotBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraSnapshotBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraSpec) DeepCopyInto(out *JiraSpec) {
+	*out = *in
+	out.Image = in.Image
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Cluster != nil {
+		in, out := &in.Cluster, &out.Cluster
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraClusterSpec)
+			**out = **in
+		}
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraUpgradeSpec)
+			**out = **in
+		}
+	}
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraDatabaseSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraExposeSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraProbeSpec)
+			**out = **in
+		}
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraStorageSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraBackupSpec)
+			**out = **in
+		}
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraMonitoringSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraSpec.
+func (in *JiraSpec) DeepCopy() *JiraSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraStatus) DeepCopyInto(out *JiraStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]JiraCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraUpgradeStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraBackupStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraRolloutStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraStatus.
+func (in *JiraStatus) DeepCopy() *JiraStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraStorageSpec) DeepCopyInto(out *JiraStorageSpec) {
+	*out = *in
+	if in.DataSource != nil {
+		in, out := &in.DataSource, &out.DataSource
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraDataSource)
+			**out = **in
+		}
+	}
+	if in.LocalHome != nil {
+		in, out := &in.LocalHome, &out.LocalHome
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraVolumeSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.SharedHome != nil {
+		in, out := &in.SharedHome, &out.SharedHome
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(JiraVolumeSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraStorageSpec.
+func (in *JiraStorageSpec) DeepCopy() *JiraStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraUpgradeSpec) DeepCopyInto(out *JiraUpgradeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraUpgradeSpec.
+func (in *JiraUpgradeSpec) DeepCopy() *JiraUpgradeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraUpgradeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraUpgradeStatus) DeepCopyInto(out *JiraUpgradeStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PhaseStartTime != nil {
+		in, out := &in.PhaseStartTime, &out.PhaseStartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraUpgradeStatus.
+func (in *JiraUpgradeStatus) DeepCopy() *JiraUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JiraVolumeSpec) DeepCopyInto(out *JiraVolumeSpec) {
+	*out = *in
+	if in.PersistentVolumeClaimSpec != nil {
+		in, out := &in.PersistentVolumeClaimSpec, &out.PersistentVolumeClaimSpec
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.PersistentVolumeClaimSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.EmptyDirVolumeSource)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JiraVolumeSpec.
+func (in *JiraVolumeSpec) DeepCopy() *JiraVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JiraVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ServiceMonitorList resource
+type ServiceMonitorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ServiceMonitor `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ServiceMonitor resource
+type ServiceMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              ServiceMonitorSpec `json:"spec"`
+}
+
+// ServiceMonitorSpec describes the Services scraped by Prometheus.
+type ServiceMonitorSpec struct {
+	// Selector selects the Services whose endpoints are scraped.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Endpoints are the ports of the Services that are scraped.
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// Endpoint defines a scrapeable port of a Service.
+type Endpoint struct {
+	// Port is the name of the Service port.
+	Port string `json:"port,omitempty"`
+
+	// Path is the HTTP path to scrape. Defaults to /metrics.
+	Path string `json:"path,omitempty"`
+
+	// Interval is the scrape interval.
+	Interval string `json:"interval,omitempty"`
+}
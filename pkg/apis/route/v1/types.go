@@ -0,0 +1,104 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RouteList resource
+type RouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Route `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Route resource
+type Route struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              RouteSpec `json:"spec"`
+}
+
+// RouteSpec describes the hostname or path the route exposes.
+type RouteSpec struct {
+	// Host is an alias/DNS that points to the service. If empty, the router
+	// generates a host name.
+	Host string `json:"host,omitempty"`
+
+	// Path that the router watches for, to route traffic to the service.
+	Path string `json:"path,omitempty"`
+
+	// To is the service the route points to.
+	To RouteTargetReference `json:"to"`
+
+	// Port is the target port on the pods selected by the service.
+	Port *RoutePort `json:"port,omitempty"`
+
+	// TLS provides the ability to configure certificates and termination.
+	TLS *TLSConfig `json:"tls,omitempty"`
+}
+
+// RouteTargetReference specifies the target that resolve into endpoints.
+type RouteTargetReference struct {
+	// Kind of the target, only Service is supported.
+	Kind string `json:"kind"`
+
+	// Name of the target.
+	Name string `json:"name"`
+}
+
+// RoutePort defines a port mapping from a router to an endpoint.
+type RoutePort struct {
+	// TargetPort is the name or number of the target port.
+	TargetPort intstr.IntOrString `json:"targetPort"`
+}
+
+// TLSTerminationType dictates where the secure communication will stop.
+type TLSTerminationType string
+
+const (
+	// TLSTerminationEdge terminates encryption at the router.
+	TLSTerminationEdge TLSTerminationType = "edge"
+	// TLSTerminationPassthrough passes encrypted traffic to the destination.
+	TLSTerminationPassthrough TLSTerminationType = "passthrough"
+	// TLSTerminationReencrypt terminates encryption at the router and
+	// re-encrypts traffic to the destination.
+	TLSTerminationReencrypt TLSTerminationType = "reencrypt"
+)
+
+// InsecureEdgeTerminationPolicyType defines the behavior for insecure
+// connections to a secure route.
+type InsecureEdgeTerminationPolicyType string
+
+const (
+	// InsecureEdgeTerminationPolicyRedirect redirects insecure traffic to https.
+	InsecureEdgeTerminationPolicyRedirect InsecureEdgeTerminationPolicyType = "Redirect"
+)
+
+// TLSConfig defines config used to secure a route.
+type TLSConfig struct {
+	// Termination indicates termination type.
+	Termination TLSTerminationType `json:"termination"`
+
+	// InsecureEdgeTerminationPolicy indicates the desired behavior for
+	// insecure connections to a route.
+	InsecureEdgeTerminationPolicy InsecureEdgeTerminationPolicyType `json:"insecureEdgeTerminationPolicy,omitempty"`
+}
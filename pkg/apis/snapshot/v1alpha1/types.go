@@ -0,0 +1,81 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VolumeSnapshotList resource
+type VolumeSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []VolumeSnapshot `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VolumeSnapshot resource
+type VolumeSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              VolumeSnapshotSpec   `json:"spec"`
+	Status            VolumeSnapshotStatus `json:"status,omitempty"`
+}
+
+// VolumeSnapshotSpec describes the source of a snapshot.
+type VolumeSnapshotSpec struct {
+	// Source is the PersistentVolumeClaim the snapshot is taken from.
+	Source *TypedLocalObjectReference `json:"source,omitempty"`
+
+	// VolumeSnapshotClassName is the name of the VolumeSnapshotClass used to
+	// take the snapshot. The default class is used if empty.
+	VolumeSnapshotClassName *string `json:"snapshotClassName,omitempty"`
+}
+
+// TypedLocalObjectReference references an object in the same namespace.
+type TypedLocalObjectReference struct {
+	// APIGroup is the group of the referenced object.
+	APIGroup *string `json:"apiGroup,omitempty"`
+
+	// Kind is the kind of the referenced object.
+	Kind string `json:"kind"`
+
+	// Name is the name of the referenced object.
+	Name string `json:"name"`
+}
+
+// VolumeSnapshotStatus is the observed state of a snapshot.
+type VolumeSnapshotStatus struct {
+	// CreationTime is the time the snapshot was taken.
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// ReadyToUse is true if the snapshot can be used to restore a volume.
+	ReadyToUse bool `json:"readyToUse"`
+
+	// Error is the last error taking the snapshot.
+	Error *VolumeSnapshotError `json:"error,omitempty"`
+}
+
+// VolumeSnapshotError describes an error taking a snapshot.
+type VolumeSnapshotError struct {
+	// Time is the time the error occurred.
+	Time metav1.Time `json:"time,omitempty"`
+
+	// Message is the error message.
+	Message string `json:"message,omitempty"`
+}
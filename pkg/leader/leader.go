@@ -0,0 +1,156 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leader elects the replica of the operator that runs the
+// controllers, so that the operator can run with multiple replicas.
+package leader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// EnabledEnvVar is the environment variable that enables leader election
+	// when set to true.
+	EnabledEnvVar = "LEADER_ELECTION"
+	// NamespaceEnvVar is the environment variable with the namespace of the
+	// lock. It defaults to the namespace of the operator Pod.
+	NamespaceEnvVar = "LEADER_ELECTION_NAMESPACE"
+	// IdentityEnvVar is the environment variable with the identity of the
+	// replica, which must be unique among the replicas. It defaults to the
+	// host name.
+	IdentityEnvVar = "LEADER_ELECTION_IDENTITY"
+	// LeaseDurationEnvVar is the environment variable with the duration of
+	// the lease, e.g. 30s. A new leader is elected at the latest after the
+	// lease of a lost leader has expired.
+	LeaseDurationEnvVar = "LEADER_ELECTION_LEASE_DURATION"
+	// DefaultLeaseDuration is the default duration of the lease.
+	DefaultLeaseDuration = 15 * time.Second
+
+	// LockName is the name of the ConfigMap that holds the lease.
+	LockName = "jira-operator-lock"
+
+	// namespaceFile holds the namespace of the Pod.
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Enabled returns true if leader election is enabled.
+func Enabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(EnabledEnvVar))
+	return enabled
+}
+
+// Run campaigns for the lease and calls run once this replica has become the
+// leader. The stop channel passed to run is closed when the lease is lost. Run
+// blocks until the lease is lost or could not be acquired.
+func Run(cfg *rest.Config, recorder record.EventRecorder, run func(stop <-chan struct{})) error {
+	namespace, err := namespace()
+	if err != nil {
+		return err
+	}
+	identity, err := identity()
+	if err != nil {
+		return err
+	}
+	leaseDuration, err := leaseDuration()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	lock, err := resourcelock.New(resourcelock.ConfigMapsResourceLock, namespace, LockName, client.CoreV1(),
+		resourcelock.ResourceLockConfig{
+			Identity:      identity,
+			EventRecorder: recorder,
+		})
+	if err != nil {
+		return err
+	}
+	// The leader gives up two thirds into the lease if it cannot renew it,
+	// which leaves time to stop before another replica takes over.
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: leaseDuration * 2 / 3,
+		RetryPeriod:   leaseDuration / 5,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(stop <-chan struct{}) {
+				log.Infof("%s became the leader", identity)
+				run(stop)
+			},
+			OnStoppedLeading: func() {
+				log.Infof("%s stopped leading", identity)
+			},
+			OnNewLeader: func(leader string) {
+				log.Infof("The current leader is %s", leader)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("Campaigning for the lease %s/%s as %s", namespace, LockName, identity)
+	le.Run()
+	return fmt.Errorf("lost the lease %s/%s", namespace, LockName)
+}
+
+// namespace returns the namespace of the lock.
+func namespace() (string, error) {
+	if ns := os.Getenv(NamespaceEnvVar); len(ns) > 0 {
+		return ns, nil
+	}
+	data, err := ioutil.ReadFile(namespaceFile)
+	if err != nil {
+		return "", fmt.Errorf("%s must be set when running outside of a cluster", NamespaceEnvVar)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// identity returns the identity of the replica.
+func identity() (string, error) {
+	if id := os.Getenv(IdentityEnvVar); len(id) > 0 {
+		return id, nil
+	}
+	return os.Hostname()
+}
+
+// leaseDuration returns the duration of the lease.
+func leaseDuration() (time.Duration, error) {
+	value := os.Getenv(LeaseDurationEnvVar)
+	if len(value) == 0 {
+		return DefaultLeaseDuration, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", LeaseDurationEnvVar, err)
+	}
+	if d < time.Second {
+		return 0, fmt.Errorf("invalid %s: must be at least 1s", LeaseDurationEnvVar)
+	}
+	return d, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics exposes the Prometheus metrics of the operator.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// PortEnvVar is the environment variable with the port of the metrics
+	// server.
+	PortEnvVar = "METRICS_PORT"
+	// DefaultPort is the default port of the metrics server.
+	DefaultPort = "8383"
+	// Path is the path of the metrics endpoint.
+	Path = "/metrics"
+
+	// namespace prefixes the names of all metrics.
+	namespace = "jira_operator"
+)
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_total",
+		Help:      "Number of reconciles of a Jira resource.",
+	}, []string{"namespace", "name"})
+
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_errors_total",
+		Help:      "Number of failed reconciles of a Jira resource.",
+	}, []string{"namespace", "name"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of the reconciles of a Jira resource.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"namespace", "name"})
+
+	createFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resource_create_failures_total",
+		Help:      "Number of resources of a Jira resource that failed to be created.",
+	}, []string{"namespace", "name", "kind"})
+
+	instancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "instances"),
+		"Number of Jira resources by phase and version.",
+		[]string{"phase", "version"}, nil)
+
+	// createFailureKinds are the kinds of the resources that failed to be
+	// created, so that their series can be deleted with the Jira resource.
+	createFailureKinds   = make(map[string]struct{})
+	createFailureKindsMu sync.Mutex
+)
+
+func init() {
+	prometheus.MustRegister(reconcileTotal, reconcileErrors, reconcileDuration, createFailures)
+}
+
+// ObserveReconcile records a reconcile of the Jira resource that took the
+// given duration and failed with the error, if not nil.
+func ObserveReconcile(namespace, name string, duration time.Duration, err error) {
+	reconcileTotal.WithLabelValues(namespace, name).Inc()
+	reconcileDuration.WithLabelValues(namespace, name).Observe(duration.Seconds())
+	if err != nil {
+		reconcileErrors.WithLabelValues(namespace, name).Inc()
+	}
+}
+
+// CreateFailed records that a resource of the given kind could not be
+// created for the Jira resource.
+func CreateFailed(namespace, name, kind string) {
+	createFailureKindsMu.Lock()
+	createFailureKinds[kind] = struct{}{}
+	createFailureKindsMu.Unlock()
+	createFailures.WithLabelValues(namespace, name, kind).Inc()
+}
+
+// Forget deletes the series of the Jira resource once it was deleted.
+func Forget(namespace, name string) {
+	reconcileTotal.DeleteLabelValues(namespace, name)
+	reconcileErrors.DeleteLabelValues(namespace, name)
+	reconcileDuration.DeleteLabelValues(namespace, name)
+	createFailureKindsMu.Lock()
+	defer createFailureKindsMu.Unlock()
+	for kind := range createFailureKinds {
+		createFailures.DeleteLabelValues(namespace, name, kind)
+	}
+}
+
+// RegisterInstances reports the number of Jira resources by phase and
+// version while the manager is running. With leader election only the leader
+// reports them.
+func RegisterInstances(mgr manager.Manager) error {
+	c := &instanceCollector{client: mgr.GetClient()}
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		if err := prometheus.Register(c); err != nil {
+			return err
+		}
+		<-stop
+		prometheus.Unregister(c)
+		return nil
+	}))
+}
+
+// instanceCollector counts the Jira resources in the cache of the manager on
+// every scrape.
+type instanceCollector struct {
+	client client.Client
+}
+
+// Describe implements prometheus.Collector.
+func (c *instanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- instancesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *instanceCollector) Collect(ch chan<- prometheus.Metric) {
+	jiras := &v1alpha1.JiraList{}
+	if err := c.client.List(context.TODO(), &client.ListOptions{}, jiras); err != nil {
+		log.Errorf("Failed to list jiras for metrics: %v", err)
+		ch <- prometheus.NewInvalidMetric(instancesDesc, err)
+		return
+	}
+	counts := make(map[[2]string]int)
+	for _, j := range jiras.Items {
+		counts[[2]string{string(j.Status.Phase), j.Spec.BaseImageVersion}]++
+	}
+	for key, n := range counts {
+		ch <- prometheus.MustNewConstMetric(instancesDesc, prometheus.GaugeValue, float64(n), key[0], key[1])
+	}
+}
+
+// Serve starts the HTTP metrics server and blocks until it fails.
+func Serve() error {
+	port := os.Getenv(PortEnvVar)
+	if len(port) == 0 {
+		port = DefaultPort
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, promhttp.Handler())
+
+	log.Infof("Serving metrics on :%s%s", port, Path)
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	return server.ListenAndServe()
+}
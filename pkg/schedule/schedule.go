@@ -0,0 +1,183 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schedule parses the cron schedules of the backups. It accepts the
+// syntax of the Kubernetes CronJob controller, so that a schedule behaves the
+// same in the Archive mode, which runs as a CronJob, and in the Snapshot mode,
+// which is scheduled by the operator.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLookback limits how far back missed schedule times are searched.
+const MaxLookback = 366 * 24 * time.Hour
+
+// macros are the predefined schedules.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field describes the values of a schedule field.
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var fields = [5]field{
+	{min: 0, max: 59},
+	{min: 0, max: 23},
+	{min: 1, max: 31},
+	{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{min: 0, max: 6, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// Schedule is a parsed five field cron schedule. Each field holds the set of
+// matching values.
+type Schedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domStar and dowStar record whether the day fields start with a
+	// wildcard, in which case a time must match both day fields.
+	domStar, dowStar bool
+}
+
+// Parse parses a standard five field cron schedule or one of the macros
+// @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly.
+// Fields support "*", "?", single values, ranges, lists, steps and the
+// three letter names of months and weekdays.
+func Parse(spec string) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if strings.HasPrefix(expr, "@") {
+		m, ok := macros[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule %q: unknown macro", spec)
+		}
+		expr = m
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", spec)
+	}
+	var sets [5]map[int]bool
+	for i, p := range parts {
+		set, err := parseField(p, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+		}
+		sets[i] = set
+	}
+	return &Schedule{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: isStar(parts[2]),
+		dowStar: isStar(parts[4]),
+	}, nil
+}
+
+// isStar returns true if the field starts with a wildcard.
+func isStar(f string) bool {
+	return strings.HasPrefix(f, "*") || strings.HasPrefix(f, "?")
+}
+
+// parseField parses a single cron field.
+func parseField(expr string, f field) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(expr, ",") {
+		step := 1
+		rng := part
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			rng = part[:i]
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" && rng != "?" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = f.value(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if strings.Contains(part, "/") {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return nil, fmt.Errorf("value %q out of range %d-%d", part, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// value returns the number or the value of the name.
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// Matches returns true if the schedule fires at the minute of t.
+func (s *Schedule) Matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// LastMissed returns the latest time after since and not after now at which
+// the schedule fired, or nil if it did not fire.
+func (s *Schedule) LastMissed(since, now time.Time) *time.Time {
+	if earliest := now.Add(-MaxLookback); since.Before(earliest) {
+		since = earliest
+	}
+	for t := now.Truncate(time.Minute); t.After(since); t = t.Add(-time.Minute) {
+		if s.Matches(t) {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "0 2 * * *"},
+		{spec: "*/15 * * * *"},
+		{spec: "*/1 * * * *"},
+		{spec: "5/20 1-5 * * ?"},
+		{spec: "0 0 1,15 jan-jun *"},
+		{spec: "0 0 * * MON-FRI"},
+		{spec: "@daily"},
+		{spec: "@Weekly"},
+		{spec: "", wantErr: true},
+		{spec: "0 2 * *", wantErr: true},
+		{spec: "0 2 * * * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "0 0 0 * *", wantErr: true},
+		{spec: "0 0 * * 7", wantErr: true},
+		{spec: "5-1 * * * *", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+		{spec: "0 0 * foo *", wantErr: true},
+		{spec: "@every 1h", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	// 2018-10-01 is a Monday.
+	date := func(day, hour, min int) time.Time {
+		return time.Date(2018, time.October, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{"0 2 * * *", date(1, 2, 0), true},
+		{"0 2 * * *", date(1, 2, 1), false},
+		{"*/1 * * * *", date(3, 17, 42), true},
+		{"*/20 * * * *", date(1, 0, 40), true},
+		{"*/20 * * * *", date(1, 0, 50), false},
+		{"10/20 * * * *", date(1, 0, 50), true},
+		{"0 0 * oct mon", date(1, 0, 0), true},
+		{"0 0 * OCT tue", date(1, 0, 0), false},
+		{"@monthly", date(1, 0, 0), true},
+		{"@monthly", date(2, 0, 0), false},
+		{"@hourly", date(5, 13, 0), true},
+		// A day matches either day field if neither starts with a wildcard.
+		{"0 0 15 * mon", date(1, 0, 0), true},
+		{"0 0 15 * mon", date(15, 0, 0), true},
+		{"0 0 15 * mon", date(16, 0, 0), false},
+		// Otherwise it must match both.
+		{"0 0 */2 * mon", date(1, 0, 0), true},
+		{"0 0 */2 * mon", date(8, 0, 0), false},
+		{"0 0 * * mon", date(2, 0, 0), false},
+		{"0 0 ? * sun", date(7, 0, 0), true},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := s.Matches(tt.t); got != tt.want {
+			t.Errorf("%q.Matches(%v) = %v, want %v", tt.spec, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestLastMissed(t *testing.T) {
+	now := time.Date(2018, time.October, 1, 12, 30, 45, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Truncate(time.Minute).Add(d)
+		return &t
+	}
+	tests := []struct {
+		name  string
+		spec  string
+		since time.Time
+		want  *time.Time
+	}{
+		{"fired since", "0 * * * *", now.Add(-2 * time.Hour), at(-30 * time.Minute)},
+		{"fired at now", "30 12 * * *", now.Add(-time.Hour), at(0)},
+		{"not fired since", "0 * * * *", now.Add(-10 * time.Minute), nil},
+		{"since is exclusive", "0 12 * * *", *at(-30 * time.Minute), nil},
+		{"latest of several", "*/10 * * * *", now.Add(-time.Hour), at(0)},
+		{"previous day", "0 2 * * *", now.Add(-48 * time.Hour), at(-10*time.Hour - 30*time.Minute)},
+		{"lookback is limited", "0 0 29 2 *", now.Add(-5 * 365 * 24 * time.Hour), nil},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q): %v", tt.name, tt.spec, err)
+		}
+		got := s.LastMissed(tt.since, now)
+		switch {
+		case got == nil && tt.want == nil:
+		case got == nil || tt.want == nil || !got.Equal(*tt.want):
+			t.Errorf("%s: LastMissed(%v, %v) = %v, want %v", tt.name, tt.since, now, got, tt.want)
+		}
+	}
+}
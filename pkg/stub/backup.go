@@ -0,0 +1,370 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SecretKeyS3AccessKeyID is the Secret key for the S3 access key.
+	SecretKeyS3AccessKeyID = "access-key-id"
+	// SecretKeyS3SecretAccessKey is the Secret key for the S3 secret key.
+	SecretKeyS3SecretAccessKey = "secret-access-key"
+
+	// backupDir is the directory the backup is assembled in before upload.
+	backupDir = "/backup"
+)
+
+// uploadBackupScript uploads the assembled backup to a new directory named
+// after the current time and removes all but the newest $RETENTION backups.
+const uploadBackupScript = `set -e
+mc config host add backup "$S3_ENDPOINT" "$S3_ACCESS_KEY_ID" "$S3_SECRET_ACCESS_KEY" > /dev/null
+target="backup/$S3_BUCKET/$S3_PREFIX"
+name="$(date -u +%Y%m%d%H%M%S)"
+mc cp --recursive ` + backupDir + `/ "$target/$name/"
+mc ls "$target/" | awk '{ print $NF }' | sort -r | tail -n +$((RETENTION + 1)) | while read old; do
+	mc rm --recursive --force "$target/$old"
+done`
+
+// h2DumpScript dumps the embedded H2 database with the H2 Script tool shipped
+// with JIRA. The database is opened read-only without a file lock as it is in
+// use by JIRA.
+const h2DumpScript = `java -cp "$(ls /opt/atlassian/jira/atlassian-jira/WEB-INF/lib/h2-*.jar | head -n 1)" org.h2.tools.Script \
+	-url "jdbc:h2:file:%s/database/h2db;ACCESS_MODE_DATA=r;FILE_LOCK=NO" -user sa -script ` + backupDir + `/database.sql`
+
+// backupName returns the name of the backup CronJob.
+func backupName(j *v1alpha1.Jira) string {
+	return fmt.Sprintf("%s-backup", j.Name)
+}
+
+// backupLabels returns the labels of the backup Jobs and Pods. They differ
+// from the JIRA labels so the backup Pods are not selected as JIRA Pods.
+func backupLabels(j *v1alpha1.Jira) map[string]string {
+	return map[string]string{
+		"app":     "jira-backup",
+		"cluster": j.Name,
+	}
+}
+
+// newJiraBackupCronJob will create or update the CronJob that backs up JIRA
+// Home and the database to object storage, or delete it if no archive backup
+// is defined.
+func newJiraBackupCronJob(j *v1alpha1.Jira) error {
+	cj := &batchv1beta1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: "batch/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            backupName(j),
+			Namespace:       j.Namespace,
+			OwnerReferences: ownerRef(j),
+			Labels:          jiraLabels(j),
+		},
+	}
+	if j.Spec.Backup == nil || j.Spec.Backup.Mode != v1alpha1.JiraBackupModeArchive {
+		return deleteResource(j, cj)
+	}
+
+	b := j.Spec.Backup
+	successfulJobs := int32(3)
+	failedJobs := int32(1)
+	backoffLimit := int32(2)
+	suspend := b.Suspend
+	cj.Spec = batchv1beta1.CronJobSpec{
+		Schedule:                   b.Schedule,
+		ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+		Suspend:                    &suspend,
+		SuccessfulJobsHistoryLimit: &successfulJobs,
+		FailedJobsHistoryLimit:     &failedJobs,
+		JobTemplate: batchv1beta1.JobTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: backupLabels(j),
+			},
+			Spec: batchv1.JobSpec{
+				BackoffLimit: &backoffLimit,
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: backupLabels(j),
+					},
+					Spec: backupPodSpec(j),
+				},
+			},
+		},
+	}
+	cj.Annotations = map[string]string{
+		specHashAnnotation: specHash(cj.Spec),
+	}
+	return reconcileResource(j, cj)
+}
+
+// backupPodSpec returns the spec of the backup Pod. Init containers dump the
+// database and archive JIRA Home and the shared home into a scratch volume
+// that is uploaded by the main container. The Pod is scheduled next to the
+// first JIRA node, which mounts the JIRA Home PVC of the backup, so a
+// ReadWriteOnce volume can be mounted by both.
+func backupPodSpec(j *v1alpha1.Jira) v1.PodSpec {
+	scratch := v1.VolumeMount{
+		Name:      "backup",
+		MountPath: backupDir,
+	}
+	spec := v1.PodSpec{
+		RestartPolicy: v1.RestartPolicyOnFailure,
+		Volumes: []v1.Volume{{
+			Name: "backup",
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		}},
+		Containers: []v1.Container{{
+			Name:         "upload",
+			Image:        j.Spec.Backup.Image,
+			Command:      []string{"/bin/sh", "-c", uploadBackupScript},
+			Env:          uploadEnv(j),
+			VolumeMounts: []v1.VolumeMount{scratch},
+		}},
+	}
+	if hasSharedHomeClaim(j) {
+		sp := j.Spec.Storage.SharedHome.MountPath
+		spec.Volumes = append(spec.Volumes, v1.Volume{
+			Name: "jira-shared-home",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+					ClaimName: sharedHomeClaimName(j),
+					ReadOnly:  true,
+				},
+			},
+		})
+		spec.InitContainers = append(spec.InitContainers, v1.Container{
+			Name:  "archive-shared-home",
+			Image: "busybox",
+			Command: []string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("tar czf %s/jira-shared-home.tar.gz -C %s .", backupDir, sp),
+			},
+			VolumeMounts: []v1.VolumeMount{scratch, {
+				Name:      "jira-shared-home",
+				MountPath: sp,
+				ReadOnly:  true,
+			}},
+		})
+	}
+	if !j.IsPVEnabled() {
+		if c := databaseDumpContainer(j); c != nil {
+			spec.InitContainers = append(spec.InitContainers, *c)
+		}
+		return spec
+	}
+
+	mp := j.Spec.DataMountPath
+	data := v1.VolumeMount{
+		Name:      "jira-data",
+		MountPath: mp,
+		ReadOnly:  true,
+	}
+	spec.Volumes = append(spec.Volumes, v1.Volume{
+		Name: "jira-data",
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+				ClaimName: dataClaimName(j, 0),
+				ReadOnly:  true,
+			},
+		},
+	})
+	if c := databaseDumpContainer(j); c != nil {
+		c.VolumeMounts = append(c.VolumeMounts, data)
+		spec.InitContainers = append(spec.InitContainers, *c)
+	}
+	spec.InitContainers = append(spec.InitContainers, v1.Container{
+		Name:  "archive-home",
+		Image: "busybox",
+		Command: []string{
+			"/bin/sh",
+			"-c",
+			fmt.Sprintf("tar czf %s/jira-home.tar.gz --exclude=./backups -C %s .", backupDir, mp),
+		},
+		VolumeMounts: []v1.VolumeMount{scratch, data},
+	})
+	spec.Affinity = &v1.Affinity{
+		PodAffinity: &v1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						appsv1.StatefulSetPodNameLabel: fmt.Sprintf("%s-0", j.Name),
+					},
+				},
+				TopologyKey: "kubernetes.io/hostname",
+			}},
+		},
+	}
+	return spec
+}
+
+// databaseDumpContainer returns the container that dumps the database to the
+// scratch volume, or nil if the database cannot be dumped.
+func databaseDumpContainer(j *v1alpha1.Jira) *v1.Container {
+	db := databaseSpec(j)
+	c := &v1.Container{
+		Name:  "dump-database",
+		Image: j.Spec.Backup.DumpImage,
+		VolumeMounts: []v1.VolumeMount{{
+			Name:      "backup",
+			MountPath: backupDir,
+		}},
+	}
+	if len(c.Image) == 0 {
+		c.Image = defaultDumpImage(j)
+	}
+	switch db.Type {
+	case v1alpha1.DatabaseTypeH2:
+		if !j.IsPVEnabled() {
+			log.Debugf("jira %s/%s has no persistent volume, skipping h2 database dump", j.Namespace, j.Name)
+			return nil
+		}
+		c.Command = []string{"/bin/sh", "-c", fmt.Sprintf(h2DumpScript, j.Spec.DataMountPath)}
+	case v1alpha1.DatabaseTypePostgres:
+		c.Command = []string{"pg_dump", "--no-owner", "--clean", "--if-exists", "-f", backupDir + "/database.sql"}
+		c.Env = postgresEnv(db)
+	case v1alpha1.DatabaseTypeMySQL:
+		c.Command = []string{
+			"/bin/sh",
+			"-c",
+			fmt.Sprintf(`mysqldump --single-transaction -h "$DB_HOST" -P "$DB_PORT" -u "$DB_USERNAME" "$DB_NAME" > %s/database.sql`, backupDir),
+		}
+		c.Env = mysqlEnv(db)
+	default:
+		log.Debugf("database type %s of jira %s/%s is not dumped", db.Type, j.Namespace, j.Name)
+		return nil
+	}
+	return c
+}
+
+// defaultDumpImage returns the image with the dump and restore tools of the
+// database type.
+func defaultDumpImage(j *v1alpha1.Jira) string {
+	switch databaseSpec(j).Type {
+	case v1alpha1.DatabaseTypePostgres:
+		return "postgres:10-alpine"
+	case v1alpha1.DatabaseTypeMySQL:
+		return "mysql:5.7"
+	}
+	return fmt.Sprintf("%s:%s", j.Spec.BaseImage, j.Spec.BaseImageVersion)
+}
+
+// postgresEnv returns the libpq environment to connect to the database.
+func postgresEnv(db v1alpha1.JiraDatabaseSpec) []v1.EnvVar {
+	return []v1.EnvVar{
+		{Name: "PGHOST", Value: db.Host},
+		{Name: "PGPORT", Value: strconv.Itoa(int(db.Port))},
+		{Name: "PGDATABASE", Value: db.Name},
+		secretEnvVar("PGUSER", db.CredentialsSecret, SecretKeyDatabaseUsername),
+		secretEnvVar("PGPASSWORD", db.CredentialsSecret, SecretKeyDatabasePassword),
+	}
+}
+
+// mysqlEnv returns the environment to connect to the database with the mysql
+// client tools.
+func mysqlEnv(db v1alpha1.JiraDatabaseSpec) []v1.EnvVar {
+	return []v1.EnvVar{
+		{Name: "DB_HOST", Value: db.Host},
+		{Name: "DB_PORT", Value: strconv.Itoa(int(db.Port))},
+		{Name: "DB_NAME", Value: db.Name},
+		secretEnvVar("DB_USERNAME", db.CredentialsSecret, SecretKeyDatabaseUsername),
+		secretEnvVar("MYSQL_PWD", db.CredentialsSecret, SecretKeyDatabasePassword),
+	}
+}
+
+// uploadEnv returns the environment of the upload container.
+func uploadEnv(j *v1alpha1.Jira) []v1.EnvVar {
+	b := j.Spec.Backup
+	return []v1.EnvVar{
+		{Name: "S3_ENDPOINT", Value: b.S3.Endpoint},
+		{Name: "S3_BUCKET", Value: b.S3.Bucket},
+		{Name: "S3_PREFIX", Value: b.S3.Prefix},
+		{Name: "RETENTION", Value: strconv.Itoa(int(b.Retention))},
+		secretEnvVar("S3_ACCESS_KEY_ID", b.S3.CredentialsSecret, SecretKeyS3AccessKeyID),
+		secretEnvVar("S3_SECRET_ACCESS_KEY", b.S3.CredentialsSecret, SecretKeyS3SecretAccessKey),
+	}
+}
+
+// observeBackup records the times of the last scheduled, successful and
+// failed backups in the status. Times are only ever moved forward, as old
+// Jobs are removed by the CronJob controller.
+func observeBackup(j *v1alpha1.Jira, status *v1alpha1.JiraStatus) {
+	if j.Spec.Backup == nil {
+		status.Backup = nil
+		return
+	}
+	if status.Backup == nil {
+		status.Backup = &v1alpha1.JiraBackupStatus{}
+	}
+	bs := status.Backup
+
+	cj := &batchv1beta1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: "batch/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backupName(j),
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(cj); err == nil && cj.Status.LastScheduleTime != nil {
+		bs.LastScheduleTime = cj.Status.LastScheduleTime
+	}
+
+	jobs := &batchv1.JobList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+	}
+	if err := listObjects(j.Namespace, backupLabels(j), jobs); err != nil {
+		log.Debugf("unable to list backup jobs: %v", err)
+		return
+	}
+	for _, job := range jobs.Items {
+		if job.Status.Succeeded > 0 && job.Status.CompletionTime != nil {
+			if bs.LastSuccessTime == nil || bs.LastSuccessTime.Before(job.Status.CompletionTime) {
+				bs.LastSuccessTime = job.Status.CompletionTime
+				recordEvent(j, v1.EventTypeNormal, "BackupSucceeded", fmt.Sprintf("Backup %s completed", job.Name))
+			}
+			continue
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Type != batchv1.JobFailed || c.Status != v1.ConditionTrue {
+				continue
+			}
+			if bs.LastFailureTime == nil || bs.LastFailureTime.Before(&c.LastTransitionTime) {
+				t := c.LastTransitionTime
+				bs.LastFailureTime = &t
+				recordEvent(j, v1.EventTypeWarning, "BackupFailed", fmt.Sprintf("Backup %s failed: %s", job.Name, c.Message))
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var (
+	// kubeClient is the client of the manager, which reads from the cache
+	// of the watched resources and writes to the API server.
+	kubeClient client.Client
+	// liveClient reads from the API server instead of the cache, so that a
+	// reconcile observes its own writes.
+	liveClient client.Client
+	// kubeClientset is used for requests the generic client cannot express.
+	kubeClientset kubernetes.Interface
+)
+
+// initClients sets up the clients and the event recorder of the operator from
+// the manager.
+func initClients(mgr manager.Manager) error {
+	c, err := client.New(mgr.GetConfig(), client.Options{
+		Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(),
+	})
+	if err != nil {
+		return err
+	}
+	cs, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	kubeClient = mgr.GetClient()
+	liveClient = c
+	kubeClientset = cs
+	eventRecorder = mgr.GetRecorder(eventSource)
+	return nil
+}
+
+// getObject reads the object with the name and namespace of the given object
+// into it from the cache.
+func getObject(o runtime.Object) error {
+	return getObjectFrom(kubeClient, o)
+}
+
+// getLiveObject reads the object with the name and namespace of the given
+// object into it from the API server. It is used where a reconcile depends on
+// an object it has just created or updated.
+func getLiveObject(o runtime.Object) error {
+	return getObjectFrom(liveClient, o)
+}
+
+// getObjectFrom reads the object with the client.
+func getObjectFrom(c client.Reader, o runtime.Object) error {
+	m, err := meta.Accessor(o)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKey{Namespace: m.GetNamespace(), Name: m.GetName()}
+	return c.Get(context.TODO(), key, o)
+}
+
+// createObject creates the object.
+func createObject(o runtime.Object) error {
+	return kubeClient.Create(context.TODO(), o)
+}
+
+// updateObject updates the object.
+func updateObject(o runtime.Object) error {
+	return kubeClient.Update(context.TODO(), o)
+}
+
+// deleteObject deletes the object. Dependents, e.g. the pods of a Job, are
+// deleted in the background.
+func deleteObject(o runtime.Object) error {
+	return kubeClient.Delete(context.TODO(), o, client.PropagationPolicy(metav1.DeletePropagationBackground))
+}
+
+// listObjects lists the objects with the given labels in the namespace.
+func listObjects(namespace string, labels map[string]string, list runtime.Object) error {
+	opts := client.InNamespace(namespace).MatchingLabels(labels)
+	return kubeClient.List(context.TODO(), opts, list)
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// clusterPropertiesScript writes the cluster.properties of the node to JIRA
+// Home. The node id is the Pod name, which is stable for StatefulSet Pods.
+const clusterPropertiesScript = `printf '%%s\n' \
+	"jira.node.id = $POD_NAME" \
+	"jira.shared.home = %s" \
+	"ehcache.listener.hostName = $POD_IP" \
+	"ehcache.listener.port = %d" \
+	"ehcache.object.port = %d" > %s/cluster.properties`
+
+// sessionAffinityAnnotations are added to the Ingress of a cluster with more
+// than one node to keep a user session on one node.
+var sessionAffinityAnnotations = map[string]string{
+	"nginx.ingress.kubernetes.io/affinity":            "cookie",
+	"nginx.ingress.kubernetes.io/session-cookie-name": "jira-route",
+	"ingress.kubernetes.io/affinity":                  "cookie",
+	"ingress.kubernetes.io/session-cookie-name":       "jira-route",
+}
+
+// replicas returns the number of JIRA nodes.
+func replicas(j *v1alpha1.Jira) int32 {
+	if j.Spec.Replicas == nil {
+		return 1
+	}
+	return *j.Spec.Replicas
+}
+
+// sharedHomeClaimName returns the name of the PVC of the shared home.
+func sharedHomeClaimName(j *v1alpha1.Jira) string {
+	return fmt.Sprintf("%s-shared-home", j.Name)
+}
+
+// newJiraSharedHomeClaim will create or update the PVC of the shared home.
+// The PVC has no owner assigned to prevent loss of data, it is reclaimed
+// according to the storage reclaim policy when the Jira resource is deleted.
+func newJiraSharedHomeClaim(j *v1alpha1.Jira) error {
+	if !hasSharedHomeClaim(j) {
+		return nil
+	}
+	pvc := &v1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sharedHomeClaimName(j),
+			Namespace: j.Namespace,
+			Labels:    jiraLabels(j),
+		},
+		Spec: *j.Spec.Storage.SharedHome.PersistentVolumeClaimSpec,
+	}
+	return reconcileResource(j, pvc)
+}
+
+// hasSharedHomeClaim returns true if the shared home is a PVC.
+func hasSharedHomeClaim(j *v1alpha1.Jira) bool {
+	return j.IsClustered() && j.Spec.Storage.SharedHome.PersistentVolumeClaimSpec != nil
+}
+
+// clusterEnv returns the environment of the init container used to render
+// cluster.properties.
+func clusterEnv(j *v1alpha1.Jira) []v1.EnvVar {
+	if !j.IsClustered() {
+		return nil
+	}
+	return []v1.EnvVar{
+		{
+			Name: "POD_NAME",
+			ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+		{
+			Name: "POD_IP",
+			ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{FieldPath: "status.podIP"},
+			},
+		},
+	}
+}
+
+// clusterPropertiesCommand returns the shell command that writes
+// cluster.properties, or an empty string if JIRA is not clustered.
+func clusterPropertiesCommand(j *v1alpha1.Jira) string {
+	if !j.IsClustered() {
+		return ""
+	}
+	c := j.Spec.Cluster
+	return fmt.Sprintf(clusterPropertiesScript, j.Spec.Storage.SharedHome.MountPath,
+		c.EhcacheListenerPort, c.EhcacheObjectPort, j.Spec.DataMountPath)
+}
+
+// clusterPorts returns the Ehcache ports of a cluster node.
+func clusterPorts(j *v1alpha1.Jira) []v1.ContainerPort {
+	if !j.IsClustered() {
+		return nil
+	}
+	return []v1.ContainerPort{
+		{ContainerPort: j.Spec.Cluster.EhcacheListenerPort, Name: "ehcache"},
+		{ContainerPort: j.Spec.Cluster.EhcacheObjectPort, Name: "ehcache-object"},
+	}
+}
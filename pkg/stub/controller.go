@@ -0,0 +1,245 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	monitoringv1 "github.com/jmckind/jira-operator/pkg/apis/monitoring/v1"
+	routev1 "github.com/jmckind/jira-operator/pkg/apis/route/v1"
+	"github.com/jmckind/jira-operator/pkg/metrics"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// progressRequeuePeriod is the delay after which a resource is reconciled
+	// again while a change is in progress, e.g. an upgrade or a restore.
+	progressRequeuePeriod = 5 * time.Second
+
+	// resyncPeriod is the delay after which a settled Jira resource is
+	// reconciled again to observe the health of JIRA and to start scheduled
+	// snapshot backups.
+	resyncPeriod = time.Minute
+)
+
+// Add creates the Jira and JiraRestore controllers and adds them to the
+// manager.
+func Add(mgr manager.Manager) error {
+	if err := initClients(mgr); err != nil {
+		return err
+	}
+	if err := addJiraController(mgr); err != nil {
+		return err
+	}
+	if err := addJiraRestoreController(mgr); err != nil {
+		return err
+	}
+	return metrics.RegisterInstances(mgr)
+}
+
+// addJiraController creates the Jira controller. Besides the Jira resources
+// it watches the resources owned by them, as well as the Pods and PVCs of
+// JIRA, which are not owned by the Jira resource but carry its labels. Routes
+// and ServiceMonitors are only watched if the API server serves them.
+func addJiraController(mgr manager.Manager) error {
+	c, err := controller.New("jira-controller", mgr, controller.Options{
+		Reconciler: &ReconcileJira{},
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.Watch(&source.Kind{Type: &v1alpha1.Jira{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	owned := []runtime.Object{
+		&v1.ConfigMap{},
+		&v1.Service{},
+		&appsv1.StatefulSet{},
+		&extv1beta1.Ingress{},
+		&batchv1beta1.CronJob{},
+		&batchv1.Job{},
+	}
+	for _, o := range []runtime.Object{&routev1.Route{}, &monitoringv1.ServiceMonitor{}} {
+		if isServed(mgr, o) {
+			owned = append(owned, o)
+		}
+	}
+	for _, o := range owned {
+		err := c.Watch(&source.Kind{Type: o}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &v1alpha1.Jira{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	labeled := []runtime.Object{
+		&v1.Pod{},
+		&v1.PersistentVolumeClaim{},
+	}
+	for _, o := range labeled {
+		err := c.Watch(&source.Kind{Type: o}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(jiraRequests),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isServed returns true if the API server serves the kind of the object,
+// which is not the case for custom resources whose CRD is not installed.
+func isServed(mgr manager.Manager, o runtime.Object) bool {
+	gvks, _, err := mgr.GetScheme().ObjectKinds(o)
+	if err != nil {
+		return false
+	}
+	gvk := gvks[0]
+	resources, err := kubeClientset.Discovery().ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Errorf("Failed to discover %s: %v", gvk.GroupVersion(), err)
+		}
+		log.Infof("%s is not served, not watching it", gvk)
+		return false
+	}
+	for _, r := range resources.APIResources {
+		if r.Kind == gvk.Kind {
+			return true
+		}
+	}
+	log.Infof("%s is not served, not watching it", gvk)
+	return false
+}
+
+// jiraRequests maps an object with the default labels of a Jira resource to
+// a request for that resource.
+func jiraRequests(o handler.MapObject) []reconcile.Request {
+	labels := o.Meta.GetLabels()
+	name, ok := labels["cluster"]
+	if !ok || labels["app"] != "jira" {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: o.Meta.GetNamespace(),
+		},
+	}}
+}
+
+// addJiraRestoreController creates the JiraRestore controller, which watches
+// the JiraRestore resources and their restore Jobs.
+func addJiraRestoreController(mgr manager.Manager) error {
+	c, err := controller.New("jirarestore-controller", mgr, controller.Options{
+		Reconciler: &ReconcileJiraRestore{},
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.Watch(&source.Kind{Type: &v1alpha1.JiraRestore{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	return c.Watch(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &v1alpha1.JiraRestore{},
+	})
+}
+
+// ReconcileJira reconciles Jira resources.
+type ReconcileJira struct{}
+
+var _ reconcile.Reconciler = &ReconcileJira{}
+
+// Reconcile brings the JIRA deployment in line with the Jira resource. Failed
+// requests are retried with the backoff of the rate limited work queue.
+func (r *ReconcileJira) Reconcile(request reconcile.Request) (result reconcile.Result, err error) {
+	log.Debugf("reconcile jira %s", request.NamespacedName)
+	j := &v1alpha1.Jira{}
+	if err := kubeClient.Get(context.TODO(), request.NamespacedName, j); errors.IsNotFound(err) {
+		log.Debugf("jira %s was deleted", request.NamespacedName)
+		metrics.Forget(request.Namespace, request.Name)
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+	start := time.Now()
+	defer func() {
+		metrics.ObserveReconcile(j.Namespace, j.Name, time.Since(start), err)
+	}()
+	if err := handleJira(j); err != nil {
+		log.Errorf("Failed to handle jira: %v", err)
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: jiraRequeuePeriod(j)}, nil
+}
+
+// jiraRequeuePeriod returns the delay after which the Jira resource is
+// reconciled again. Progress of upgrades, rollouts, snapshot backups and
+// restores is polled, as it is not fully reflected by watched resources.
+func jiraRequeuePeriod(j *v1alpha1.Jira) time.Duration {
+	if j.DeletionTimestamp != nil || j.Status.Phase != v1alpha1.JiraPhaseRunning {
+		return progressRequeuePeriod
+	}
+	if u := j.Status.Upgrade; u != nil && !u.IsFinished() {
+		return progressRequeuePeriod
+	}
+	if j.Status.Rollout != nil || isScaledDown(j) {
+		return progressRequeuePeriod
+	}
+	return resyncPeriod
+}
+
+// ReconcileJiraRestore reconciles JiraRestore resources.
+type ReconcileJiraRestore struct{}
+
+var _ reconcile.Reconciler = &ReconcileJiraRestore{}
+
+// Reconcile advances the restore. Unfinished restores are polled until they
+// have completed or failed.
+func (r *ReconcileJiraRestore) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Debugf("reconcile jira restore %s", request.NamespacedName)
+	restore := &v1alpha1.JiraRestore{}
+	if err := kubeClient.Get(context.TODO(), request.NamespacedName, restore); errors.IsNotFound(err) {
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := handleJiraRestore(restore); err != nil {
+		log.Errorf("Failed to handle jira restore: %v", err)
+		return reconcile.Result{}, err
+	}
+	if restore.Status.IsFinished() || restore.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{RequeueAfter: progressRequeuePeriod}, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"text/template"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+)
+
+// poolTemplate is the connection pool configuration shared by all drivers.
+const poolTemplate = `{{define "pool"}}
+		<pool-min-size>{{.PoolMinSize}}</pool-min-size>
+		<pool-max-size>{{.PoolMaxSize}}</pool-max-size>
+		<pool-max-wait>30000</pool-max-wait>{{if .ValidationQuery}}
+		<validation-query>{{xml .ValidationQuery}}</validation-query>{{end}}
+		<min-evictable-idle-time-millis>{{.MinEvictableIdleTime}}</min-evictable-idle-time-millis>
+		<time-between-eviction-runs-millis>{{.EvictionRunsInterval}}</time-between-eviction-runs-millis>
+		<pool-max-idle>{{.PoolMaxSize}}</pool-max-idle>
+		<pool-remove-abandoned>true</pool-remove-abandoned>
+		<pool-remove-abandoned-timeout>300</pool-remove-abandoned-timeout>
+		<pool-test-on-borrow>false</pool-test-on-borrow>
+		<pool-test-while-idle>{{if .ValidationQuery}}true{{else}}false{{end}}</pool-test-while-idle>{{end}}`
+
+// dbConfigTemplates are the dbconfig.xml templates for each database type.
+var dbConfigTemplates = map[v1alpha1.DatabaseType]string{
+	v1alpha1.DatabaseTypeH2: `<?xml version="1.0" encoding="UTF-8"?>
+<jira-database-config>
+	<name>defaultDS</name>
+	<delegator-name>default</delegator-name>
+	<database-type>h2</database-type>
+	<schema-name>{{xml .Schema}}</schema-name>
+	<jdbc-datasource>
+		<url>jdbc:h2:file:{{xml .DataMountPath}}/database/h2db</url>
+		<driver-class>org.h2.Driver</driver-class>
+		<username>sa</username>
+		<password></password>{{template "pool" .}}
+	</jdbc-datasource>
+</jira-database-config>
+`,
+	v1alpha1.DatabaseTypePostgres: `<?xml version="1.0" encoding="UTF-8"?>
+<jira-database-config>
+	<name>defaultDS</name>
+	<delegator-name>default</delegator-name>
+	<database-type>postgres72</database-type>
+	<schema-name>{{xml .Schema}}</schema-name>
+	<jdbc-datasource>
+		<url>jdbc:postgresql://{{xml .Host}}:{{.Port}}/{{xml .Name}}</url>
+		<driver-class>org.postgresql.Driver</driver-class>
+		<username>{{xml .Username}}</username>
+		<password>{{xml .Password}}</password>{{template "pool" .}}
+	</jdbc-datasource>
+</jira-database-config>
+`,
+	v1alpha1.DatabaseTypeMySQL: `<?xml version="1.0" encoding="UTF-8"?>
+<jira-database-config>
+	<name>defaultDS</name>
+	<delegator-name>default</delegator-name>
+	<database-type>mysql57</database-type>
+	<jdbc-datasource>
+		<url>jdbc:mysql://address=(protocol=tcp)(host={{xml .Host}})(port={{.Port}})/{{xml .Name}}?useUnicode=true&amp;characterEncoding=UTF8&amp;sessionVariables=default_storage_engine=InnoDB</url>
+		<driver-class>com.mysql.jdbc.Driver</driver-class>
+		<username>{{xml .Username}}</username>
+		<password>{{xml .Password}}</password>{{template "pool" .}}
+	</jdbc-datasource>
+</jira-database-config>
+`,
+	v1alpha1.DatabaseTypeMSSQL: `<?xml version="1.0" encoding="UTF-8"?>
+<jira-database-config>
+	<name>defaultDS</name>
+	<delegator-name>default</delegator-name>
+	<database-type>mssql</database-type>
+	<schema-name>{{xml .Schema}}</schema-name>
+	<jdbc-datasource>
+		<url>jdbc:sqlserver://{{xml .Host}}:{{.Port}};databaseName={{xml .Name}}</url>
+		<driver-class>com.microsoft.sqlserver.jdbc.SQLServerDriver</driver-class>
+		<username>{{xml .Username}}</username>
+		<password>{{xml .Password}}</password>{{template "pool" .}}
+	</jdbc-datasource>
+</jira-database-config>
+`,
+	v1alpha1.DatabaseTypeOracle: `<?xml version="1.0" encoding="UTF-8"?>
+<jira-database-config>
+	<name>defaultDS</name>
+	<delegator-name>default</delegator-name>
+	<database-type>oracle10g</database-type>
+	<jdbc-datasource>
+		<url>jdbc:oracle:thin:@//{{xml .Host}}:{{.Port}}/{{xml .Name}}</url>
+		<driver-class>oracle.jdbc.OracleDriver</driver-class>
+		<username>{{xml .Username}}</username>
+		<password>{{xml .Password}}</password>{{template "pool" .}}
+	</jdbc-datasource>
+</jira-database-config>
+`,
+}
+
+// dbConfig holds the values used to render a dbconfig.xml template.
+type dbConfig struct {
+	v1alpha1.JiraDatabaseSpec
+
+	// DataMountPath is the path of JIRA Home.
+	DataMountPath string
+
+	// Username is the placeholder for the database user.
+	Username string
+
+	// Password is the placeholder for the database password.
+	Password string
+
+	// MinEvictableIdleTime is the time in milliseconds after which an idle
+	// connection may be evicted from the pool.
+	MinEvictableIdleTime int
+
+	// EvictionRunsInterval is the time in milliseconds between the runs of
+	// the idle connection evictor.
+	EvictionRunsInterval int
+}
+
+// databaseSpec returns the database spec of the JIRA resource, falling back to
+// the embedded H2 database.
+func databaseSpec(j *v1alpha1.Jira) v1alpha1.JiraDatabaseSpec {
+	if j.Spec.Database != nil {
+		return *j.Spec.Database
+	}
+	db := v1alpha1.JiraDatabaseSpec{Type: v1alpha1.DatabaseTypeH2}
+	db.SetDefaults()
+	return db
+}
+
+// renderDatabaseConfig renders the dbconfig.xml for the JIRA resource. The
+// credentials are left as placeholders that are replaced by the init container
+// so they are never stored in the ConfigMap.
+func renderDatabaseConfig(j *v1alpha1.Jira) (string, error) {
+	cfg := dbConfig{
+		JiraDatabaseSpec:     databaseSpec(j),
+		DataMountPath:        j.Spec.DataMountPath,
+		MinEvictableIdleTime: 60000,
+		EvictionRunsInterval: 300000,
+	}
+	text, ok := dbConfigTemplates[cfg.Type]
+	if !ok {
+		return "", fmt.Errorf("unsupported database type %q", cfg.Type)
+	}
+	if cfg.Type == v1alpha1.DatabaseTypeH2 {
+		// The embedded database keeps the eviction settings of the default
+		// dbconfig.xml.
+		cfg.MinEvictableIdleTime = 4000
+		cfg.EvictionRunsInterval = 5000
+	} else {
+		cfg.Username = dbUsernamePlaceholder
+		cfg.Password = dbPasswordPlaceholder
+	}
+
+	tmpl, err := template.New(string(cfg.Type)).Funcs(template.FuncMap{"xml": xmlEscape}).Parse(poolTemplate + text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, cfg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// xmlEscape returns the value escaped for use in XML text.
+func xmlEscape(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
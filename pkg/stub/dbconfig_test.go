@@ -0,0 +1,147 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+)
+
+func newDatabaseJira(db *v1alpha1.JiraDatabaseSpec) *v1alpha1.Jira {
+	if db != nil {
+		db.SetDefaults()
+	}
+	return &v1alpha1.Jira{
+		Spec: v1alpha1.JiraSpec{
+			DataMountPath: "/var/atlassian/jira",
+			Database:      db,
+		},
+	}
+}
+
+func TestRenderDatabaseConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		database *v1alpha1.JiraDatabaseSpec
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "default h2",
+			contains: []string{
+				"<database-type>h2</database-type>",
+				"<url>jdbc:h2:file:/var/atlassian/jira/database/h2db</url>",
+				"<username>sa</username>",
+				"<min-evictable-idle-time-millis>4000</min-evictable-idle-time-millis>",
+				"<time-between-eviction-runs-millis>5000</time-between-eviction-runs-millis>",
+				"<pool-test-while-idle>false</pool-test-while-idle>",
+			},
+			excludes: []string{dbUsernamePlaceholder, dbPasswordPlaceholder, "<validation-query>"},
+		},
+		{
+			name:     "postgres",
+			database: &v1alpha1.JiraDatabaseSpec{Type: v1alpha1.DatabaseTypePostgres, Host: "db", Name: "jira"},
+			contains: []string{
+				"<database-type>postgres72</database-type>",
+				"<url>jdbc:postgresql://db:5432/jira</url>",
+				"<username>" + dbUsernamePlaceholder + "</username>",
+				"<password>" + dbPasswordPlaceholder + "</password>",
+				"<min-evictable-idle-time-millis>60000</min-evictable-idle-time-millis>",
+				"<time-between-eviction-runs-millis>300000</time-between-eviction-runs-millis>",
+			},
+		},
+		{
+			name: "mysql pool",
+			database: &v1alpha1.JiraDatabaseSpec{
+				Type:            v1alpha1.DatabaseTypeMySQL,
+				Host:            "db",
+				Name:            "jira",
+				PoolMinSize:     5,
+				PoolMaxSize:     40,
+				ValidationQuery: "select 1",
+			},
+			contains: []string{
+				"<database-type>mysql57</database-type>",
+				"(host=db)(port=3306)/jira?",
+				"<pool-min-size>5</pool-min-size>",
+				"<pool-max-size>40</pool-max-size>",
+				"<pool-max-idle>40</pool-max-idle>",
+				"<validation-query>select 1</validation-query>",
+				"<pool-test-while-idle>true</pool-test-while-idle>",
+			},
+		},
+		{
+			name:     "mssql",
+			database: &v1alpha1.JiraDatabaseSpec{Type: v1alpha1.DatabaseTypeMSSQL, Host: "db", Name: "jira"},
+			contains: []string{
+				"<database-type>mssql</database-type>",
+				"<url>jdbc:sqlserver://db:1433;databaseName=jira</url>",
+				"<username>" + dbUsernamePlaceholder + "</username>",
+			},
+		},
+		{
+			name:     "oracle",
+			database: &v1alpha1.JiraDatabaseSpec{Type: v1alpha1.DatabaseTypeOracle, Host: "db", Name: "jira"},
+			contains: []string{
+				"<database-type>oracle10g</database-type>",
+				"<url>jdbc:oracle:thin:@//db:1521/jira</url>",
+				"<password>" + dbPasswordPlaceholder + "</password>",
+			},
+		},
+		{
+			name: "escaped values",
+			database: &v1alpha1.JiraDatabaseSpec{
+				Type:            v1alpha1.DatabaseTypePostgres,
+				Host:            "db",
+				Name:            "jira&<test>",
+				ValidationQuery: "select 1 where 'a' < 'b'",
+			},
+			contains: []string{
+				"<url>jdbc:postgresql://db:5432/jira&amp;&lt;test&gt;</url>",
+				"<validation-query>select 1 where &#39;a&#39; &lt; &#39;b&#39;</validation-query>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := renderDatabaseConfig(newDatabaseJira(tt.database))
+		if err != nil {
+			t.Errorf("%s: renderDatabaseConfig() error = %v", tt.name, err)
+			continue
+		}
+		if err := xml.Unmarshal([]byte(got), new(struct{})); err != nil {
+			t.Errorf("%s: renderDatabaseConfig() is not valid XML: %v\n%s", tt.name, err, got)
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(got, s) {
+				t.Errorf("%s: renderDatabaseConfig() does not contain %q\n%s", tt.name, s, got)
+			}
+		}
+		for _, s := range tt.excludes {
+			if strings.Contains(got, s) {
+				t.Errorf("%s: renderDatabaseConfig() contains %q\n%s", tt.name, s, got)
+			}
+		}
+	}
+}
+
+func TestRenderDatabaseConfigUnsupportedType(t *testing.T) {
+	j := newDatabaseJira(&v1alpha1.JiraDatabaseSpec{Type: "db2"})
+	if _, err := renderDatabaseConfig(j); err == nil {
+		t.Error("renderDatabaseConfig() error = nil, want an error for an unsupported database type")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"reflect"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// persistDefaults writes the defaulted spec back to the Jira resource, so the
+// effective spec is visible on the stored object. It is a no-op if the
+// defaults were already applied, e.g. by the mutating webhook.
+func persistDefaults(j, defaulted *v1alpha1.Jira) error {
+	if j.DeletionTimestamp != nil || reflect.DeepEqual(j.Spec, defaulted.Spec) {
+		return nil
+	}
+	j.Spec = *defaulted.Spec.DeepCopy()
+	if err := updateObject(j); err != nil {
+		log.Errorf("Failed to persist defaults: %v", err)
+		return err
+	}
+	return nil
+}
+
+// persistRestoreDefaults writes the defaulted spec back to the JiraRestore
+// resource.
+func persistRestoreDefaults(r, defaulted *v1alpha1.JiraRestore) error {
+	if reflect.DeepEqual(r.Spec, defaulted.Spec) {
+		return nil
+	}
+	r.Spec = *defaulted.Spec.DeepCopy()
+	if err := updateObject(r); err != nil {
+		log.Errorf("Failed to persist restore defaults: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// eventSource is the component reported as the source of Events.
+const eventSource = "jira-operator"
+
+// eventRecorder records the Events of the operator. Repeated Events with the
+// same type, reason and message are aggregated by the recorder.
+var eventRecorder record.EventRecorder
+
+// recordEvent records an Event on the Jira resource, so that the actions of
+// the operator show up in `kubectl describe jira`. The Event refers to the
+// resource explicitly as the client clears the TypeMeta of objects it reads.
+func recordEvent(j *v1alpha1.Jira, eventType, reason, message string) {
+	ref := &v1.ObjectReference{
+		Kind:            "Jira",
+		APIVersion:      v1alpha1.SchemeGroupVersion.String(),
+		Name:            j.Name,
+		Namespace:       j.Namespace,
+		UID:             j.UID,
+		ResourceVersion: j.ResourceVersion,
+	}
+	eventRecorder.Event(ref, eventType, reason, message)
+}
+
+// describeResource returns the kind and name of an object for Event messages,
+// e.g. "StatefulSet jira".
+func describeResource(o runtime.Object) string {
+	kind := reflect.TypeOf(o).Elem().Name()
+	m, err := meta.Accessor(o)
+	if err != nil {
+		return kind
+	}
+	return fmt.Sprintf("%s %s", kind, m.GetName())
+}
@@ -0,0 +1,244 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	routev1 "github.com/jmckind/jira-operator/pkg/apis/route/v1"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ingressClassAnnotation is the annotation used to select the ingress controller.
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// serviceType returns the type of the JIRA service.
+func serviceType(j *v1alpha1.Jira) v1.ServiceType {
+	if j.Spec.Expose != nil && len(j.Spec.Expose.ServiceType) > 0 {
+		return j.Spec.Expose.ServiceType
+	}
+	return v1.ServiceTypeNodePort
+}
+
+// newJiraIngress will create or update the JIRA Ingress, or delete it if no
+// Ingress is defined.
+func newJiraIngress(j *v1alpha1.Jira) error {
+	ing := &extv1beta1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: "extensions/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            j.Name,
+			Namespace:       j.Namespace,
+			OwnerReferences: ownerRef(j),
+			Labels:          jiraLabels(j),
+		},
+	}
+	if j.Spec.Expose == nil || j.Spec.Expose.Ingress == nil {
+		return deleteResource(j, ing)
+	}
+
+	spec := j.Spec.Expose.Ingress
+	ing.Annotations = make(map[string]string)
+	if replicas(j) > 1 {
+		for key, val := range sessionAffinityAnnotations {
+			ing.Annotations[key] = val
+		}
+	}
+	for key, val := range spec.Annotations {
+		ing.Annotations[key] = val
+	}
+	if len(spec.IngressClass) > 0 {
+		ing.Annotations[ingressClassAnnotation] = spec.IngressClass
+	}
+	ing.Spec = extv1beta1.IngressSpec{
+		Rules: []extv1beta1.IngressRule{{
+			Host: spec.Host,
+			IngressRuleValue: extv1beta1.IngressRuleValue{
+				HTTP: &extv1beta1.HTTPIngressRuleValue{
+					Paths: []extv1beta1.HTTPIngressPath{{
+						Path: spec.Path,
+						Backend: extv1beta1.IngressBackend{
+							ServiceName: j.Name,
+							ServicePort: intstr.FromString("http"),
+						},
+					}},
+				},
+			},
+		}},
+	}
+	if len(spec.TLSSecretName) > 0 {
+		ing.Spec.TLS = []extv1beta1.IngressTLS{{
+			Hosts:      []string{spec.Host},
+			SecretName: spec.TLSSecretName,
+		}}
+	}
+	return reconcileResource(j, ing)
+}
+
+// newJiraRoute will create or update the JIRA Route, or delete it if no Route
+// is defined.
+func newJiraRoute(j *v1alpha1.Jira) error {
+	route := &routev1.Route{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Route",
+			APIVersion: "route.openshift.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            j.Name,
+			Namespace:       j.Namespace,
+			OwnerReferences: ownerRef(j),
+			Labels:          jiraLabels(j),
+		},
+	}
+	if j.Spec.Expose == nil || j.Spec.Expose.Route == nil {
+		return deleteResource(j, route)
+	}
+
+	spec := j.Spec.Expose.Route
+	path := spec.Path
+	if len(path) == 0 {
+		path = contextPath(j)
+	}
+	route.Spec = routev1.RouteSpec{
+		Host: spec.Host,
+		Path: path,
+		To: routev1.RouteTargetReference{
+			Kind: "Service",
+			Name: j.Name,
+		},
+		Port: &routev1.RoutePort{
+			TargetPort: intstr.FromString("http"),
+		},
+	}
+	if len(spec.TLSTermination) > 0 {
+		route.Spec.TLS = &routev1.TLSConfig{
+			Termination:                   routev1.TLSTerminationType(spec.TLSTermination),
+			InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+		}
+	}
+	return reconcileResource(j, route)
+}
+
+// deleteResource will delete the resource if it exists and is owned by the
+// Jira resource. Failures to look up the resource are ignored, as the API of
+// optional resources may not be available in the cluster.
+func deleteResource(j *v1alpha1.Jira, o runtime.Object) error {
+	if err := getObject(o); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Debugf("unable to get resource %T: %v", o, err)
+		}
+		return nil
+	}
+	if obj, ok := o.(metav1.Object); !ok || !metav1.IsControlledBy(obj, j) {
+		return nil
+	}
+	log.Infof("deleting resource %T", o)
+	if err := deleteObject(o); err != nil && !errors.IsNotFound(err) {
+		log.Errorf("Failed to delete resource: %v", err)
+		recordEvent(j, v1.EventTypeWarning, "DeleteFailed", fmt.Sprintf("Failed to delete %s: %v", describeResource(o), err))
+		return err
+	}
+	recordEvent(j, v1.EventTypeNormal, "Deleted", fmt.Sprintf("Deleted %s", describeResource(o)))
+	return nil
+}
+
+// externalURL returns the URL JIRA is exposed at outside of the cluster, or
+// nil if JIRA is not exposed by an Ingress or a Route with a host name.
+func externalURL(j *v1alpha1.Jira) *url.URL {
+	e := j.Spec.Expose
+	switch {
+	case e == nil:
+		return nil
+	case e.Ingress != nil && len(e.Ingress.Host) > 0:
+		u := &url.URL{Scheme: "http", Host: e.Ingress.Host, Path: e.ContextPath}
+		if len(e.Ingress.TLSSecretName) > 0 {
+			u.Scheme = "https"
+		}
+		return u
+	case e.Route != nil && len(e.Route.Host) > 0:
+		u := &url.URL{Scheme: "http", Host: e.Route.Host, Path: e.ContextPath}
+		if len(e.Route.TLSTermination) > 0 {
+			u.Scheme = "https"
+		}
+		return u
+	}
+	return nil
+}
+
+// contextPath returns the path JIRA is served from, or an empty string for
+// the root path.
+func contextPath(j *v1alpha1.Jira) string {
+	if j.Spec.Expose == nil {
+		return ""
+	}
+	return j.Spec.Expose.ContextPath
+}
+
+// proxyEnv returns the environment variables that configure the reverse proxy
+// settings and the context path of the Tomcat connector in server.xml. The
+// settings are derived from the Ingress or Route and can be overridden by the
+// proxy spec. The variables are understood by the cptactionhank/atlassian-jira
+// image.
+func proxyEnv(j *v1alpha1.Jira) []v1.EnvVar {
+	env := make([]v1.EnvVar, 0)
+	if cp := contextPath(j); len(cp) > 0 {
+		env = append(env, v1.EnvVar{Name: "X_PATH", Value: cp})
+	}
+
+	var name, scheme string
+	var port int32
+	if u := externalURL(j); u != nil {
+		name = u.Hostname()
+		scheme = u.Scheme
+		port = 80
+		if scheme == "https" {
+			port = 443
+		}
+	}
+	if p := j.Spec.Expose; p != nil && p.Proxy != nil {
+		if len(p.Proxy.Name) > 0 {
+			name = p.Proxy.Name
+		}
+		if len(p.Proxy.Scheme) > 0 {
+			scheme = p.Proxy.Scheme
+		}
+		if p.Proxy.Port > 0 {
+			port = p.Proxy.Port
+		}
+	}
+	if len(name) == 0 {
+		return env
+	}
+	env = append(env, v1.EnvVar{Name: "X_PROXY_NAME", Value: name})
+	if port > 0 {
+		env = append(env, v1.EnvVar{Name: "X_PROXY_PORT", Value: strconv.Itoa(int(port))})
+	}
+	if len(scheme) > 0 {
+		env = append(env, v1.EnvVar{Name: "X_PROXY_SCHEME", Value: scheme})
+	}
+	return env
+}
@@ -0,0 +1,226 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	snapshotv1alpha1 "github.com/jmckind/jira-operator/pkg/apis/snapshot/v1alpha1"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// storageFinalizer is the finalizer that holds back the deletion of a Jira
+// resource until its storage has been reclaimed.
+const storageFinalizer = "app.redhat.com/reclaim-storage"
+
+// hasFinalizer returns true if the Jira resource has the storage finalizer.
+func hasFinalizer(j *v1alpha1.Jira) bool {
+	for _, f := range j.Finalizers {
+		if f == storageFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureFinalizer adds the storage finalizer to the Jira resource.
+func ensureFinalizer(j *v1alpha1.Jira) error {
+	if hasFinalizer(j) {
+		return nil
+	}
+	j.Finalizers = append(j.Finalizers, storageFinalizer)
+	if err := updateObject(j); err != nil {
+		log.Errorf("Failed to add finalizer: %v", err)
+		return err
+	}
+	return nil
+}
+
+// removeFinalizer removes the storage finalizer from the Jira resource, which
+// allows the resource to be deleted.
+func removeFinalizer(j *v1alpha1.Jira) error {
+	finalizers := make([]string, 0, len(j.Finalizers))
+	for _, f := range j.Finalizers {
+		if f != storageFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	j.Finalizers = finalizers
+	if err := updateObject(j); err != nil && !errors.IsNotFound(err) {
+		log.Errorf("Failed to remove finalizer: %v", err)
+		return err
+	}
+	return nil
+}
+
+// finalizeJira reclaims the storage of a deleted Jira resource according to
+// its reclaim policy and returns true once the finalizer can be removed. JIRA
+// is stopped first so the volumes are no longer in use.
+func finalizeJira(j *v1alpha1.Jira) (bool, error) {
+	ss := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      j.Name,
+			Namespace: j.Namespace,
+		},
+	}
+	if err := deleteResource(j, ss); err != nil {
+		return false, err
+	}
+	pods := &v1.PodList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+	}
+	if err := listObjects(j.Namespace, defaultLabels(j), pods); err != nil {
+		return false, err
+	}
+	if len(pods.Items) > 0 {
+		log.Debugf("waiting for %d jira pods to terminate", len(pods.Items))
+		return false, nil
+	}
+
+	pvcs := &v1.PersistentVolumeClaimList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+	}
+	if err := listObjects(j.Namespace, defaultLabels(j), pvcs); err != nil {
+		return false, err
+	}
+	names := make([]string, 0, len(pvcs.Items))
+	for _, pvc := range pvcs.Items {
+		names = append(names, pvc.Name)
+	}
+	if len(names) == 0 {
+		return true, nil
+	}
+
+	switch policy := j.Spec.Storage.ReclaimPolicy; policy {
+	case v1alpha1.StorageReclaimDelete:
+		for i := range pvcs.Items {
+			if err := deleteClaim(&pvcs.Items[i]); err != nil {
+				return false, err
+			}
+		}
+		recordEvent(j, v1.EventTypeNormal, "StorageDeleted",
+			fmt.Sprintf("Deleted persistent volume claims %s", strings.Join(names, ", ")))
+	case v1alpha1.StorageReclaimSnapshot:
+		ready := true
+		for i := range pvcs.Items {
+			ok, err := snapshotClaim(j, &pvcs.Items[i])
+			if err != nil {
+				return false, err
+			}
+			ready = ready && ok
+		}
+		if !ready {
+			return false, nil
+		}
+		recordEvent(j, v1.EventTypeNormal, "StorageSnapshotted",
+			fmt.Sprintf("Deleted persistent volume claims %s after taking snapshots", strings.Join(names, ", ")))
+	default:
+		if policy != v1alpha1.StorageReclaimRetain {
+			recordEvent(j, v1.EventTypeWarning, "InvalidReclaimPolicy",
+				fmt.Sprintf("Unknown reclaim policy %q, retaining storage", policy))
+		}
+		recordEvent(j, v1.EventTypeNormal, "StorageRetained",
+			fmt.Sprintf("Retained persistent volume claims %s", strings.Join(names, ", ")))
+	}
+	return true, nil
+}
+
+// deleteClaim deletes the PVC.
+func deleteClaim(pvc *v1.PersistentVolumeClaim) error {
+	pvc.TypeMeta = metav1.TypeMeta{
+		Kind:       "PersistentVolumeClaim",
+		APIVersion: "v1",
+	}
+	log.Infof("deleting persistent volume claim %s/%s", pvc.Namespace, pvc.Name)
+	if err := deleteObject(pvc); err != nil && !errors.IsNotFound(err) {
+		log.Errorf("Failed to delete persistent volume claim: %v", err)
+		return err
+	}
+	return nil
+}
+
+// snapshotClaim takes a VolumeSnapshot of the PVC and deletes the PVC once the
+// snapshot is ready. It returns true if the PVC has been deleted. The snapshot
+// has no owner so that it outlives the Jira resource. A failed snapshot sets
+// the ReclaimFailed condition and holds back the deletion until the reclaim
+// policy is changed.
+func snapshotClaim(j *v1alpha1.Jira, pvc *v1.PersistentVolumeClaim) (bool, error) {
+	snap := &snapshotv1alpha1.VolumeSnapshot{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "VolumeSnapshot",
+			APIVersion: "snapshot.storage.k8s.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", pvc.Name, j.DeletionTimestamp.UTC().Format("20060102150405")),
+			Namespace: j.Namespace,
+			Labels:    jiraLabels(j),
+		},
+		Spec: snapshotv1alpha1.VolumeSnapshotSpec{
+			Source: &snapshotv1alpha1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: pvc.Name,
+			},
+		},
+	}
+	if sc := j.Spec.Storage.SnapshotClassName; len(sc) > 0 {
+		snap.Spec.VolumeSnapshotClassName = &sc
+	}
+
+	err := getLiveObject(snap)
+	if errors.IsNotFound(err) {
+		log.Infof("creating snapshot %s/%s of persistent volume claim %s", snap.Namespace, snap.Name, pvc.Name)
+		if err := createObject(snap); err != nil {
+			recordEvent(j, v1.EventTypeWarning, "SnapshotFailed",
+				fmt.Sprintf("Failed to create snapshot of persistent volume claim %s: %v", pvc.Name, err))
+			return false, err
+		}
+		recordEvent(j, v1.EventTypeNormal, "SnapshotCreated",
+			fmt.Sprintf("Created snapshot %s of persistent volume claim %s", snap.Name, pvc.Name))
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if e := snap.Status.Error; e != nil {
+		// The claim is kept until the reclaim policy is changed, a failed
+		// snapshot is not retried.
+		msg := fmt.Sprintf("Snapshot %s of persistent volume claim %s failed: %s. "+
+			"Set spec.storage.reclaimPolicy to Retain or Delete to finish the deletion", snap.Name, pvc.Name, e.Message)
+		recordEvent(j, v1.EventTypeWarning, "SnapshotFailed", msg)
+		j.Status.SetCondition(v1alpha1.JiraConditionReclaimFailed, v1.ConditionTrue, "SnapshotFailed", msg)
+		return false, nil
+	}
+	if !snap.Status.ReadyToUse {
+		return false, nil
+	}
+	return true, deleteClaim(pvc)
+}
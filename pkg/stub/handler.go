@@ -15,89 +15,123 @@
 package stub
 
 import (
-	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	"github.com/jmckind/jira-operator/pkg/metrics"
 
-	"github.com/operator-framework/operator-sdk/pkg/sdk"
 	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-// DefaultDatabaseConfig is the default configuration for the JIRA database.
-const DefaultDatabaseConfig = `<?xml version="1.0" encoding="UTF-8"?>
-<jira-database-config>
-	<name>defaultDS</name>
-	<delegator-name>default</delegator-name>
-	<database-type>h2</database-type>
-	<schema-name>PUBLIC</schema-name>
-	<jdbc-datasource>
-		<url>jdbc:h2:file:/var/atlassian/jira/database/h2db</url>
-		<driver-class>org.h2.Driver</driver-class>
-		<username>sa</username>
-		<password></password>
-		<pool-min-size>20</pool-min-size>
-		<pool-max-size>20</pool-max-size>
-		<pool-max-wait>30000</pool-max-wait>
-		<min-evictable-idle-time-millis>4000</min-evictable-idle-time-millis>
-		<time-between-eviction-runs-millis>5000</time-between-eviction-runs-millis>
-		<pool-max-idle>20</pool-max-idle>
-		<pool-remove-abandoned>true</pool-remove-abandoned>
-		<pool-remove-abandoned-timeout>300</pool-remove-abandoned-timeout>
-	</jdbc-datasource>
-</jira-database-config>
-`
-
-// NewJiraHandler constructs JiraHandler objects
-func NewJiraHandler() sdk.Handler {
-	return &JiraHandler{}
-}
-
-// JiraHandler handles requests for Jira!
-type JiraHandler struct {
-	// Fill me
-}
+// handleJira will create the resources for the JIRA deployment and update the
+// status of the custom resource.
+func handleJira(j *v1alpha1.Jira) (err error) {
+	log.Debug("handle jira")
+	jira := j.DeepCopy()
+	jira.SetDefaults()
+	if err = persistDefaults(j, jira); err != nil {
+		return err
+	}
 
-// Handle is the starting point for processing new events.
-func (h *JiraHandler) Handle(ctx context.Context, event sdk.Event) error {
-	log.Debug("handle event")
-	switch o := event.Object.(type) {
-	case *v1alpha1.Jira:
-		err := handleJira(o)
-		if err != nil {
-			log.Errorf("Failed to handle jira: %v", err)
-			return err
+	if jira.DeletionTimestamp != nil {
+		if !hasFinalizer(j) {
+			return nil
+		}
+		done, ferr := finalizeJira(jira)
+		if done {
+			return removeFinalizer(j)
+		}
+		err = ferr
+	} else if err = ensureFinalizer(j); err == nil {
+		if err = releaseRestore(j, jira); err == nil {
+			err = reconcileUpgrade(jira)
+		}
+		if err == nil {
+			err = reconcileSnapshotBackup(jira)
+		}
+		if err == nil {
+			err = reconcileRollout(jira)
+		}
+		if err == nil {
+			err = createJiraResources(jira)
 		}
 	}
-	return nil
+	if serr := updateStatus(j, jira, err); serr != nil && err == nil {
+		err = serr
+	}
+	return
 }
 
-// handleJira will create the resources for the JIRA deployment
-func handleJira(j *v1alpha1.Jira) (err error) {
-	log.Debug("handle jira")
-	j.SetDefaults()
-
+// createJiraResources will create the resources for the JIRA deployment
+func createJiraResources(j *v1alpha1.Jira) (err error) {
+	if err = validateDatabaseSecret(j); err != nil {
+		if isInvalidSpec(err) {
+			log.Errorf("Invalid database credentials: %v", err)
+			recordEvent(j, v1.EventTypeWarning, "ValidationFailed", fmt.Sprintf("Invalid database credentials: %v", err))
+		}
+		return
+	}
+	if err = j.ValidateStorage().ToAggregate(); err != nil {
+		log.Errorf("Invalid storage spec: %v", err)
+		recordEvent(j, v1.EventTypeWarning, "ValidationFailed", fmt.Sprintf("Invalid storage spec: %v", err))
+		return invalidSpec(err)
+	}
+	if err = j.ValidateCluster().ToAggregate(); err != nil {
+		log.Errorf("Invalid cluster spec: %v", err)
+		recordEvent(j, v1.EventTypeWarning, "ValidationFailed", fmt.Sprintf("Invalid cluster spec: %v", err))
+		return invalidSpec(err)
+	}
 	if err = newJiraConfigMap(j); err != nil {
 		return
 	}
-	if err = newJiraPVC(j); err != nil {
+	if err = newJMXExporterConfigMap(j); err != nil {
+		return
+	}
+	if err = newJiraHeadlessService(j); err != nil {
+		return
+	}
+	if err = newJiraDataClaim(j); err != nil {
 		return
 	}
-	if err = newJiraPod(j); err != nil {
+	if err = newJiraSharedHomeClaim(j); err != nil {
+		return
+	}
+	if err = newJiraStatefulSet(j); err != nil {
 		return
 	}
 	if err = newJiraService(j); err != nil {
 		return
 	}
+	if err = newJiraServiceMonitor(j); err != nil {
+		return
+	}
+	if err = newJiraIngress(j); err != nil {
+		return
+	}
+	if err = newJiraRoute(j); err != nil {
+		return
+	}
+	if err = newJiraBackupCronJob(j); err != nil {
+		return
+	}
 	return nil
 }
 
-// newJiraConfigMap will create a JIRA ConfigMap
+// newJiraConfigMap will create or update a JIRA ConfigMap with the dbconfig.xml
+// rendered from the database spec.
 func newJiraConfigMap(j *v1alpha1.Jira) error {
+	dbconfig, err := renderDatabaseConfig(j)
+	if err != nil {
+		log.Errorf("Failed to render database config: %v", err)
+		return invalidSpec(err)
+	}
 	cm := &v1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ConfigMap",
@@ -110,18 +144,22 @@ func newJiraConfigMap(j *v1alpha1.Jira) error {
 			Labels:          jiraLabels(j),
 		},
 		Data: map[string]string{
-			"dbconfig.xml": DefaultDatabaseConfig,
+			"dbconfig.xml": dbconfig,
 		},
 	}
-	return createResource(j, cm)
+	return reconcileResource(j, cm)
 }
 
-// newJiraPod will create a JIRA Pod
-func newJiraPod(j *v1alpha1.Jira) error {
-	pod := &v1.Pod{
+// newJiraStatefulSet will create or update the JIRA StatefulSet
+func newJiraStatefulSet(j *v1alpha1.Jira) error {
+	replicas := replicas(j)
+	if isScaledDown(j) {
+		replicas = 0
+	}
+	ss := &appsv1.StatefulSet{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "Pod",
-			APIVersion: "v1",
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            j.Name,
@@ -129,33 +167,98 @@ func newJiraPod(j *v1alpha1.Jira) error {
 			OwnerReferences: ownerRef(j),
 			Labels:          jiraLabels(j),
 		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: headlessServiceName(j),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: defaultLabels(j),
+			},
+			Template:             jiraPodTemplate(j),
+			VolumeClaimTemplates: jiraVolumeClaimTemplates(j),
+			UpdateStrategy:       jiraUpdateStrategy(j),
+		},
+	}
+	ss.Annotations = map[string]string{
+		specHashAnnotation: specHash(ss.Spec.Template),
+	}
+	return reconcileResource(j, ss)
+}
+
+// jiraPodTemplate returns the pod template of the JIRA StatefulSet. Nodes of
+// a cluster are labeled as in service so they can be drained from the
+// Service during a rolling restart.
+func jiraPodTemplate(j *v1alpha1.Jira) v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: podLabels(j),
+		},
 		Spec: jiraPodSpec(j),
 	}
-	return createResource(j, pod)
 }
 
-// newJiraPV will create a JIRA PersistentVolume. There is no owner assigned to
-// prevent loss of data. The user must manually claen up the PVC.
-func newJiraPVC(j *v1alpha1.Jira) error {
+// podLabels returns the labels of the JIRA Pods, which are also the selector
+// of the JIRA Service.
+func podLabels(j *v1alpha1.Jira) map[string]string {
+	labels := jiraLabels(j)
+	if j.IsClustered() {
+		labels[inServiceLabel] = "true"
+	}
+	return labels
+}
+
+// jiraVolumeClaimTemplates returns the claim templates for JIRA Home. The
+// resulting PVCs have no owner assigned to prevent loss of data. They are
+// reclaimed according to the storage reclaim policy when the Jira resource is
+// deleted.
+func jiraVolumeClaimTemplates(j *v1alpha1.Jira) []v1.PersistentVolumeClaim {
 	if !j.IsPVEnabled() {
 		return nil
 	}
-	pvc := &v1.PersistentVolumeClaim{
+	return []v1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "jira-data",
+			Labels: jiraLabels(j),
+		},
+		Spec: *j.LocalHomeClaimSpec(),
+	}}
+}
+
+// dataClaimName returns the name of the PVC created by the StatefulSet for
+// JIRA Home of the given ordinal.
+func dataClaimName(j *v1alpha1.Jira, ordinal int) string {
+	return fmt.Sprintf("jira-data-%s-%d", j.Name, ordinal)
+}
+
+// headlessServiceName returns the name of the governing service of the
+// StatefulSet.
+func headlessServiceName(j *v1alpha1.Jira) string {
+	return fmt.Sprintf("%s-headless", j.Name)
+}
+
+// newJiraHeadlessService will create or update the governing Service that
+// provides stable network identities for the StatefulSet Pods.
+func newJiraHeadlessService(j *v1alpha1.Jira) error {
+	svc := &v1.Service{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "PersistentVolumeClaim",
+			Kind:       "Service",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      j.Name,
-			Namespace: j.Namespace,
-			Labels:    jiraLabels(j),
+			Name:            headlessServiceName(j),
+			Namespace:       j.Namespace,
+			OwnerReferences: ownerRef(j),
+			Labels:          jiraLabels(j),
+		},
+		Spec: v1.ServiceSpec{
+			Selector:  defaultLabels(j),
+			ClusterIP: v1.ClusterIPNone,
+			Ports:     servicePorts(j),
 		},
-		Spec: *j.Spec.Pod.PersistentVolumeClaimSpec,
 	}
-	return createResource(j, pvc)
+	return reconcileResource(j, svc)
 }
 
-// newJiraService will create a JIRA Service
+// newJiraService will create or update a JIRA Service
 func newJiraService(j *v1alpha1.Jira) error {
 	svc := &v1.Service{
 		TypeMeta: metav1.TypeMeta{
@@ -166,16 +269,17 @@ func newJiraService(j *v1alpha1.Jira) error {
 			Name:            j.Name,
 			Namespace:       j.Namespace,
 			OwnerReferences: ownerRef(j),
-			Labels:          jiraLabels(j),
+			Labels:          serviceLabels(j),
 		},
 		Spec: v1.ServiceSpec{
-			Selector:        jiraLabels(j),
+			Selector: podLabels(j),
+			// Sessions of a Data Center cluster are bound to a node.
 			SessionAffinity: "ClientIP",
-			Type:            "NodePort",
+			Type:            serviceType(j),
 			Ports:           servicePorts(j),
 		},
 	}
-	return createResource(j, svc)
+	return reconcileResource(j, svc)
 }
 
 // defaultLabels returns the default labels.
@@ -208,38 +312,48 @@ func ownerRef(j *v1alpha1.Jira) []metav1.OwnerReference {
 
 func initContainers(j *v1alpha1.Jira) []v1.Container {
 	result := make([]v1.Container, 0)
-	if !j.IsPVEnabled() {
-		return result
-	}
 
 	mp := j.Spec.DataMountPath
+	script := fmt.Sprintf(renderDatabaseConfigScript, mp)
+	if cmd := clusterPropertiesCommand(j); len(cmd) > 0 {
+		script += "; " + cmd
+	}
+	script += fmt.Sprintf("; chown -R 2:2 %s", mp)
+	if j.IsClustered() {
+		script += " " + j.Spec.Storage.SharedHome.MountPath
+	}
 	ic := v1.Container{
 		Name:  "init",
 		Image: "busybox",
 		Command: []string{
 			"/bin/sh",
 			"-c",
-			fmt.Sprintf("cp /etc/jira/dbconfig.xml %s/; chown -R 2:2 %s", mp, mp),
+			script,
 		},
+		Env:          append(initEnv(j), clusterEnv(j)...),
 		VolumeMounts: initVolumeMounts(j),
 	}
 	result = append(result, ic)
-	return result
+	return append(result, monitoringInitContainers(j)...)
 }
 
 func jiraContainers(j *v1alpha1.Jira) []v1.Container {
-	return []v1.Container{{
+	containers := []v1.Container{{
 		Name:  "jira",
 		Image: fmt.Sprintf("%s:%s", j.Spec.BaseImage, j.Spec.BaseImageVersion),
-		Ports: []v1.ContainerPort{{
+		Ports: append([]v1.ContainerPort{{
 			ContainerPort: 8080,
 			Name:          "http",
-		}},
-		Resources:    containerResources(j),
-		Stdin:        true,
-		TTY:          true,
-		VolumeMounts: jiraVolumeMounts(j),
+		}}, append(clusterPorts(j), monitoringPorts(j)...)...),
+		Env:            jiraEnv(j),
+		Resources:      containerResources(j),
+		ReadinessProbe: readinessProbe(j),
+		LivenessProbe:  livenessProbe(j),
+		Stdin:          true,
+		TTY:            true,
+		VolumeMounts:   append(append(jiraVolumeMounts(j), secretVolumeMount()), monitoringVolumeMounts(j)...),
 	}}
+	return append(containers, monitoringContainers(j)...)
 }
 
 // jiraPodSpec returns a PodSpec for a JIRA container.
@@ -253,10 +367,17 @@ func jiraPodSpec(j *v1alpha1.Jira) v1.PodSpec {
 
 // servicePorts returns the ports for the JIRA service.
 func servicePorts(j *v1alpha1.Jira) []v1.ServicePort {
-	return []v1.ServicePort{{
+	ports := []v1.ServicePort{{
 		Port: 8080,
 		Name: "http",
 	}}
+	if isMonitoringEnabled(j) {
+		ports = append(ports, v1.ServicePort{
+			Port: j.Spec.Monitoring.Port,
+			Name: "metrics",
+		})
+	}
+	return ports
 }
 
 // containerResources returns the resources requestd for the application.
@@ -270,26 +391,24 @@ func containerResources(j *v1alpha1.Jira) v1.ResourceRequirements {
 
 func jiraVolumeMounts(j *v1alpha1.Jira) (mounts []v1.VolumeMount) {
 	mounts = make([]v1.VolumeMount, 0)
-	if j.IsPVEnabled() {
+	mounts = append(mounts, v1.VolumeMount{
+		Name:      "jira-data",
+		MountPath: j.Spec.DataMountPath,
+	})
+	if j.IsClustered() {
 		mounts = append(mounts, v1.VolumeMount{
-			Name:      "jira-data",
-			MountPath: j.Spec.DataMountPath,
+			Name:      "jira-shared-home",
+			MountPath: j.Spec.Storage.SharedHome.MountPath,
 		})
 	}
 	return
 }
 
 func initVolumeMounts(j *v1alpha1.Jira) []v1.VolumeMount {
-	return []v1.VolumeMount{
-		{
-			Name:      "jira-data",
-			MountPath: j.Spec.DataMountPath,
-		},
-		{
-			Name:      "jira-config",
-			MountPath: "/etc/jira",
-		},
-	}
+	return append(jiraVolumeMounts(j), v1.VolumeMount{
+		Name:      "jira-config",
+		MountPath: "/etc/jira",
+	})
 }
 
 func jiraVolumes(j *v1alpha1.Jira) []v1.Volume {
@@ -307,28 +426,51 @@ func jiraVolumes(j *v1alpha1.Jira) []v1.Volume {
 			},
 		},
 	}
-	volumes = append(volumes, cmv)
+	volumes = append(volumes, cmv, secretVolume(j))
 
-	if j.IsPVEnabled() {
-		pv := v1.Volume{
-			Name: "jira-data",
-			VolumeSource: v1.VolumeSource{
+	// Without a PVC spec the local JIRA Home lives in an emptyDir, otherwise
+	// the volume is provided by the StatefulSet claim template.
+	if !j.IsPVEnabled() {
+		var local *v1alpha1.JiraVolumeSpec
+		if j.Spec.Storage != nil {
+			local = j.Spec.Storage.LocalHome
+		}
+		volumes = append(volumes, v1.Volume{
+			Name:         "jira-data",
+			VolumeSource: emptyDirSource(local),
+		})
+	}
+
+	// The shared home is a single PVC mounted by all nodes. A single node
+	// may use an emptyDir instead.
+	if j.IsClustered() {
+		shared := v1.Volume{
+			Name:         "jira-shared-home",
+			VolumeSource: emptyDirSource(j.Spec.Storage.SharedHome),
+		}
+		if hasSharedHomeClaim(j) {
+			shared.VolumeSource = v1.VolumeSource{
 				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
-					ClaimName: j.Name},
-			},
+					ClaimName: sharedHomeClaimName(j),
+				},
+			}
 		}
-		volumes = append(volumes, pv)
+		volumes = append(volumes, shared)
 	}
-	return volumes
+	return append(volumes, monitoringVolumes(j)...)
 }
 
-func createResource(j *v1alpha1.Jira, o sdk.Object) error {
-	err := sdk.Create(o)
+func createResource(j *v1alpha1.Jira, o runtime.Object) error {
+	err := createObject(o)
 	if errors.IsAlreadyExists(err) {
 		log.Debug("resource already exists")
+		return nil
 	} else if err != nil {
 		log.Errorf("Failed to create resource: %v", err)
+		metrics.CreateFailed(j.Namespace, j.Name, reflect.TypeOf(o).Elem().Name())
+		recordEvent(j, v1.EventTypeWarning, "CreateFailed", fmt.Sprintf("Failed to create %s: %v", describeResource(o), err))
 		return err
 	}
+	recordEvent(j, v1.EventTypeNormal, "Created", fmt.Sprintf("Created %s", describeResource(o)))
 	return nil
 }
@@ -0,0 +1,288 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	monitoringv1 "github.com/jmckind/jira-operator/pkg/apis/monitoring/v1"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// jmxExporterAgentDir holds the JMX exporter Java agent in the JIRA
+	// container.
+	jmxExporterAgentDir = "/opt/jmx-exporter"
+	// jmxExporterConfigDir holds the configuration of the JMX exporter.
+	jmxExporterConfigDir = "/etc/jmx-exporter"
+	// jmxExporterConfigKey is the key of the configuration in the ConfigMap.
+	jmxExporterConfigKey = "config.yaml"
+	// jmxExporterAgentImage is the image used to download the Java agent. It
+	// is pinned so that the init container does not change between rollouts.
+	jmxExporterAgentImage = "appropriate/curl:3.1"
+
+	// metricsLabel marks the Service scraped by the ServiceMonitor, as the
+	// headless Service selects the same Pods.
+	metricsLabel = "app.redhat.com/metrics"
+)
+
+// defaultJMXExporterConfig exports the JVM, the Tomcat thread pools and
+// request processors, and the JIRA MBeans, which include the database pool
+// and the indexing queue.
+const defaultJMXExporterConfig = `lowercaseOutputName: true
+lowercaseOutputLabelNames: true
+whitelistObjectNames:
+- "java.lang:*"
+- "Catalina:type=ThreadPool,*"
+- "Catalina:type=GlobalRequestProcessor,*"
+- "com.atlassian.jira:*"
+rules:
+- pattern: ".*"
+`
+
+// isMonitoringEnabled returns true if a JMX exporter is requested.
+func isMonitoringEnabled(j *v1alpha1.Jira) bool {
+	return j.Spec.Monitoring != nil
+}
+
+// isMonitoringSidecar returns true if the JMX exporter runs in a sidecar.
+func isMonitoringSidecar(j *v1alpha1.Jira) bool {
+	return isMonitoringEnabled(j) && j.Spec.Monitoring.Mode == v1alpha1.JiraMonitoringModeSidecar
+}
+
+// jmxExporterName returns the name of the ConfigMap and the ServiceMonitor of
+// the JMX exporter.
+func jmxExporterName(j *v1alpha1.Jira) string {
+	return fmt.Sprintf("%s-jmx-exporter", j.Name)
+}
+
+// jmxExporterConfig returns the configuration of the JMX exporter. The
+// sidecar connects to the JMX remote connector of JIRA in the same Pod.
+func jmxExporterConfig(j *v1alpha1.Jira) string {
+	m := j.Spec.Monitoring
+	config := m.Config
+	if len(strings.TrimSpace(config)) == 0 {
+		config = defaultJMXExporterConfig
+	}
+	if isMonitoringSidecar(j) {
+		config = fmt.Sprintf("hostPort: 127.0.0.1:%d\n", m.JMXPort) + config
+	}
+	return config
+}
+
+// newJMXExporterConfigMap will create or update the ConfigMap with the
+// configuration of the JMX exporter, or delete it if monitoring is disabled.
+func newJMXExporterConfigMap(j *v1alpha1.Jira) error {
+	cm := &v1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            jmxExporterName(j),
+			Namespace:       j.Namespace,
+			OwnerReferences: ownerRef(j),
+			Labels:          jiraLabels(j),
+		},
+	}
+	if !isMonitoringEnabled(j) {
+		return deleteResource(j, cm)
+	}
+	cm.Data = map[string]string{
+		jmxExporterConfigKey: jmxExporterConfig(j),
+	}
+	return reconcileResource(j, cm)
+}
+
+// newJiraServiceMonitor will create or update the ServiceMonitor that scrapes
+// the JMX exporter through the JIRA Service, or delete it if it is not
+// requested.
+func newJiraServiceMonitor(j *v1alpha1.Jira) error {
+	sm := &monitoringv1.ServiceMonitor{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceMonitor",
+			APIVersion: "monitoring.coreos.com/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            jmxExporterName(j),
+			Namespace:       j.Namespace,
+			OwnerReferences: ownerRef(j),
+			Labels:          jiraLabels(j),
+		},
+	}
+	if !isMonitoringEnabled(j) || j.Spec.Monitoring.ServiceMonitor == nil {
+		return deleteResource(j, sm)
+	}
+	spec := j.Spec.Monitoring.ServiceMonitor
+	for key, val := range spec.Labels {
+		sm.Labels[key] = val
+	}
+	selector := defaultLabels(j)
+	selector[metricsLabel] = "true"
+	sm.Spec = monitoringv1.ServiceMonitorSpec{
+		Selector: metav1.LabelSelector{MatchLabels: selector},
+		Endpoints: []monitoringv1.Endpoint{{
+			Port:     "metrics",
+			Path:     "/metrics",
+			Interval: spec.Interval,
+		}},
+	}
+	return reconcileResource(j, sm)
+}
+
+// mergeServiceMonitor updates the spec of a ServiceMonitor.
+func mergeServiceMonitor(live, desired *monitoringv1.ServiceMonitor) bool {
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	if !reflect.DeepEqual(live.Spec, desired.Spec) {
+		live.Spec = desired.Spec
+		changed = true
+	}
+	return changed
+}
+
+// serviceLabels returns the labels of the JIRA Service.
+func serviceLabels(j *v1alpha1.Jira) map[string]string {
+	labels := jiraLabels(j)
+	if isMonitoringEnabled(j) {
+		labels[metricsLabel] = "true"
+	}
+	return labels
+}
+
+// monitoringJavaOpts returns the JVM flags that load the Java agent or
+// enable the JMX remote connector for the sidecar. The connector has no
+// authentication, so it only listens on the loopback interface shared with
+// the sidecar.
+func monitoringJavaOpts(j *v1alpha1.Jira) string {
+	m := j.Spec.Monitoring
+	if isMonitoringSidecar(j) {
+		return strings.Join([]string{
+			"-Dcom.sun.management.jmxremote",
+			fmt.Sprintf("-Dcom.sun.management.jmxremote.port=%d", m.JMXPort),
+			fmt.Sprintf("-Dcom.sun.management.jmxremote.rmi.port=%d", m.JMXPort),
+			"-Dcom.sun.management.jmxremote.host=127.0.0.1",
+			"-Dcom.sun.management.jmxremote.local.only=false",
+			"-Dcom.sun.management.jmxremote.authenticate=false",
+			"-Dcom.sun.management.jmxremote.ssl=false",
+			"-Djava.rmi.server.hostname=127.0.0.1",
+		}, " ")
+	}
+	return fmt.Sprintf("-javaagent:%s/jmx_prometheus_javaagent.jar=%d:%s/%s",
+		jmxExporterAgentDir, m.Port, jmxExporterConfigDir, jmxExporterConfigKey)
+}
+
+// monitoringEnv returns the environment of the JIRA container that passes the
+// JVM flags of the JMX exporter to Tomcat.
+func monitoringEnv(j *v1alpha1.Jira) []v1.EnvVar {
+	if !isMonitoringEnabled(j) {
+		return nil
+	}
+	return []v1.EnvVar{{Name: "CATALINA_OPTS", Value: monitoringJavaOpts(j)}}
+}
+
+// metricsPort returns the container port the metrics are served on.
+func metricsPort(j *v1alpha1.Jira) v1.ContainerPort {
+	return v1.ContainerPort{ContainerPort: j.Spec.Monitoring.Port, Name: "metrics"}
+}
+
+// monitoringPorts returns the ports of the JIRA container of the Java agent.
+func monitoringPorts(j *v1alpha1.Jira) []v1.ContainerPort {
+	if !isMonitoringEnabled(j) || isMonitoringSidecar(j) {
+		return nil
+	}
+	return []v1.ContainerPort{metricsPort(j)}
+}
+
+// monitoringVolumeMounts returns the mounts of the JIRA container of the Java
+// agent and its configuration.
+func monitoringVolumeMounts(j *v1alpha1.Jira) []v1.VolumeMount {
+	if !isMonitoringEnabled(j) || isMonitoringSidecar(j) {
+		return nil
+	}
+	return []v1.VolumeMount{
+		{Name: "jmx-exporter", MountPath: jmxExporterAgentDir},
+		{Name: "jmx-exporter-config", MountPath: jmxExporterConfigDir},
+	}
+}
+
+// monitoringInitContainers returns the init container that downloads the
+// Java agent.
+func monitoringInitContainers(j *v1alpha1.Jira) []v1.Container {
+	if !isMonitoringEnabled(j) || isMonitoringSidecar(j) {
+		return nil
+	}
+	return []v1.Container{{
+		Name:  "jmx-exporter-agent",
+		Image: jmxExporterAgentImage,
+		Command: []string{
+			"/bin/sh",
+			"-c",
+			fmt.Sprintf(`curl -fsSL -o %s/jmx_prometheus_javaagent.jar "$AGENT_URL"`, jmxExporterAgentDir),
+		},
+		Env: []v1.EnvVar{{Name: "AGENT_URL", Value: j.Spec.Monitoring.AgentURL}},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "jmx-exporter", MountPath: jmxExporterAgentDir},
+		},
+	}}
+}
+
+// monitoringContainers returns the JMX exporter sidecar.
+func monitoringContainers(j *v1alpha1.Jira) []v1.Container {
+	if !isMonitoringSidecar(j) {
+		return nil
+	}
+	return []v1.Container{{
+		Name:  "jmx-exporter",
+		Image: j.Spec.Monitoring.Image,
+		Ports: []v1.ContainerPort{metricsPort(j)},
+		Env: []v1.EnvVar{
+			{Name: "SERVICE_PORT", Value: fmt.Sprint(j.Spec.Monitoring.Port)},
+			{Name: "CONFIG_YML", Value: jmxExporterConfigDir + "/" + jmxExporterConfigKey},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "jmx-exporter-config", MountPath: jmxExporterConfigDir},
+		},
+	}}
+}
+
+// monitoringVolumes returns the volumes of the Java agent and the
+// configuration of the JMX exporter.
+func monitoringVolumes(j *v1alpha1.Jira) []v1.Volume {
+	if !isMonitoringEnabled(j) {
+		return nil
+	}
+	volumes := []v1.Volume{{
+		Name: "jmx-exporter-config",
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: jmxExporterName(j),
+				},
+			},
+		},
+	}}
+	if !isMonitoringSidecar(j) {
+		volumes = append(volumes, v1.Volume{
+			Name:         "jmx-exporter",
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		})
+	}
+	return volumes
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// probeSpec returns the probe spec of the JIRA resource with defaults applied.
+func probeSpec(j *v1alpha1.Jira) v1alpha1.JiraProbeSpec {
+	p := v1alpha1.JiraProbeSpec{}
+	if j.Spec.Probes != nil {
+		p = *j.Spec.Probes
+	}
+	p.SetDefaults()
+	return p
+}
+
+// statusHandler returns an HTTP GET handler for the JIRA status endpoint.
+func statusHandler(j *v1alpha1.Jira) v1.Handler {
+	return v1.Handler{
+		HTTPGet: &v1.HTTPGetAction{
+			Path: contextPath(j) + "/status",
+			Port: intstr.FromString("http"),
+		},
+	}
+}
+
+// readinessProbe returns the readiness probe of the JIRA container. JIRA
+// only receives traffic from the Service once its status endpoint reports
+// that it is running.
+func readinessProbe(j *v1alpha1.Jira) *v1.Probe {
+	p := probeSpec(j)
+	return &v1.Probe{
+		Handler:          statusHandler(j),
+		PeriodSeconds:    p.PeriodSeconds,
+		TimeoutSeconds:   p.TimeoutSeconds,
+		FailureThreshold: p.ReadinessFailureThreshold,
+	}
+}
+
+// livenessProbe returns the liveness probe of the JIRA container. The probe
+// only starts after the startup period so a slow startup is not mistaken for
+// a locked up JIRA.
+func livenessProbe(j *v1alpha1.Jira) *v1.Probe {
+	p := probeSpec(j)
+	return &v1.Probe{
+		Handler:             statusHandler(j),
+		InitialDelaySeconds: p.StartupSeconds,
+		PeriodSeconds:       p.PeriodSeconds,
+		TimeoutSeconds:      p.TimeoutSeconds,
+		FailureThreshold:    p.LivenessFailureThreshold,
+	}
+}
+
+// observeHealth sets the Healthy condition from the probe results of the JIRA
+// containers.
+func observeHealth(j *v1alpha1.Jira, status *v1alpha1.JiraStatus) bool {
+	pods := &v1.PodList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+	}
+	if err := listObjects(j.Namespace, defaultLabels(j), pods); err != nil {
+		status.SetCondition(v1alpha1.JiraConditionHealthy, v1.ConditionUnknown, "Error", err.Error())
+		return false
+	}
+
+	var unhealthy []string
+	found := 0
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != "jira" {
+				continue
+			}
+			found++
+			if cs.Ready {
+				continue
+			}
+			msg := fmt.Sprintf("%s is not ready", pod.Name)
+			if t := cs.LastTerminationState.Terminated; t != nil {
+				msg = fmt.Sprintf("%s is not ready, restarted %d times, last exit: %s", pod.Name, cs.RestartCount, t.Reason)
+			}
+			unhealthy = append(unhealthy, msg)
+		}
+	}
+	switch {
+	case found == 0:
+		status.SetCondition(v1alpha1.JiraConditionHealthy, v1.ConditionFalse, "NoContainers", "no jira containers are running")
+		return false
+	case len(unhealthy) > 0:
+		status.SetCondition(v1alpha1.JiraConditionHealthy, v1.ConditionFalse, "ProbeFailed", strings.Join(unhealthy, "; "))
+		return false
+	}
+	status.SetCondition(v1alpha1.JiraConditionHealthy, v1.ConditionTrue, "ProbeSucceeded",
+		fmt.Sprintf("%d jira containers are ready", found))
+	return true
+}
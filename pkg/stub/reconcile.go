@@ -0,0 +1,312 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	monitoringv1 "github.com/jmckind/jira-operator/pkg/apis/monitoring/v1"
+	routev1 "github.com/jmckind/jira-operator/pkg/apis/route/v1"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// specHashAnnotation records the hash of the desired spec on objects whose
+// spec cannot be compared field by field with the live object.
+const specHashAnnotation = "app.redhat.com/spec-hash"
+
+// reconcileResource creates the desired object if it does not exist yet,
+// otherwise it brings the live object in line with the desired one.
+func reconcileResource(j *v1alpha1.Jira, desired runtime.Object) error {
+	live := desired.DeepCopyObject().(runtime.Object)
+	err := getObject(live)
+	if errors.IsNotFound(err) {
+		return createResource(j, desired)
+	} else if err != nil {
+		log.Errorf("Failed to get resource: %v", err)
+		return err
+	}
+
+	var changed bool
+	switch d := desired.(type) {
+	case *v1.ConfigMap:
+		changed = mergeConfigMap(j, live.(*v1.ConfigMap), d)
+	case *v1.PersistentVolumeClaim:
+		changed = mergePVC(live.(*v1.PersistentVolumeClaim), d)
+	case *v1.Service:
+		changed = mergeService(live.(*v1.Service), d)
+	case *appsv1.StatefulSet:
+		changed = mergeStatefulSet(live.(*appsv1.StatefulSet), d)
+	case *extv1beta1.Ingress:
+		changed = mergeIngress(live.(*extv1beta1.Ingress), d)
+	case *routev1.Route:
+		changed = mergeRoute(live.(*routev1.Route), d)
+	case *batchv1beta1.CronJob:
+		changed = mergeCronJob(live.(*batchv1beta1.CronJob), d)
+	case *monitoringv1.ServiceMonitor:
+		changed = mergeServiceMonitor(live.(*monitoringv1.ServiceMonitor), d)
+	default:
+		return fmt.Errorf("unsupported resource type %T", desired)
+	}
+	if !changed {
+		return nil
+	}
+	return updateResource(j, live)
+}
+
+// updateResource will update an existing resource. A conflict means the
+// resource was read from a stale cache, the update is retried by the next
+// reconcile.
+func updateResource(j *v1alpha1.Jira, o runtime.Object) error {
+	log.Debugf("updating resource %T", o)
+	if err := updateObject(o); errors.IsConflict(err) {
+		log.Debugf("resource %T changed, retrying update: %v", o, err)
+		return err
+	} else if err != nil {
+		log.Errorf("Failed to update resource: %v", err)
+		recordEvent(j, v1.EventTypeWarning, "UpdateFailed", fmt.Sprintf("Failed to update %s: %v", describeResource(o), err))
+		return err
+	}
+	recordEvent(j, v1.EventTypeNormal, "Updated", fmt.Sprintf("Updated %s", describeResource(o)))
+	return nil
+}
+
+// mergeLabels adds the desired labels to the live labels and returns true if
+// the live labels were changed.
+func mergeLabels(live *metav1.ObjectMeta, desired metav1.ObjectMeta) bool {
+	changed := false
+	if live.Labels == nil {
+		live.Labels = make(map[string]string)
+	}
+	for key, val := range desired.Labels {
+		if live.Labels[key] != val {
+			live.Labels[key] = val
+			changed = true
+		}
+	}
+	return changed
+}
+
+// mergeConfigMap updates the data of a ConfigMap owned by the Jira resource.
+// ConfigMaps provided by the user are never modified.
+func mergeConfigMap(j *v1alpha1.Jira, live, desired *v1.ConfigMap) bool {
+	if !metav1.IsControlledBy(live, j) {
+		log.Debugf("configmap %s is not owned by jira %s, skipping update", live.Name, j.Name)
+		return false
+	}
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	if !reflect.DeepEqual(live.Data, desired.Data) {
+		live.Data = desired.Data
+		changed = true
+	}
+	return changed
+}
+
+// mergePVC updates the storage request of a PersistentVolumeClaim. All other
+// fields of the claim spec are immutable.
+func mergePVC(live, desired *v1.PersistentVolumeClaim) bool {
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	want, ok := desired.Spec.Resources.Requests[v1.ResourceStorage]
+	if !ok {
+		return changed
+	}
+	if have := live.Spec.Resources.Requests[v1.ResourceStorage]; have.Cmp(want) != 0 {
+		if live.Spec.Resources.Requests == nil {
+			live.Spec.Resources.Requests = make(v1.ResourceList)
+		}
+		live.Spec.Resources.Requests[v1.ResourceStorage] = want
+		changed = true
+	}
+	return changed
+}
+
+// mergeService updates the mutable fields of a Service. The cluster IP and
+// any allocated node ports are preserved.
+func mergeService(live, desired *v1.Service) bool {
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	ports := make([]v1.ServicePort, len(desired.Spec.Ports))
+	for i, p := range desired.Spec.Ports {
+		ports[i] = p
+		for _, lp := range live.Spec.Ports {
+			if lp.Name == p.Name && p.NodePort == 0 && desired.Spec.Type == live.Spec.Type {
+				ports[i].NodePort = lp.NodePort
+			}
+		}
+		if ports[i].Protocol == "" {
+			ports[i].Protocol = v1.ProtocolTCP
+		}
+		if ports[i].TargetPort.IntVal == 0 && ports[i].TargetPort.StrVal == "" {
+			ports[i].TargetPort.IntVal = p.Port
+		}
+	}
+	spec := live.Spec.DeepCopy()
+	spec.Type = desired.Spec.Type
+	spec.Selector = desired.Spec.Selector
+	spec.SessionAffinity = desired.Spec.SessionAffinity
+	spec.Ports = ports
+	if !reflect.DeepEqual(&live.Spec, spec) {
+		live.Spec = *spec
+		changed = true
+	}
+	return changed
+}
+
+// mergeStatefulSet updates the replicas, the update strategy and the pod
+// template of a StatefulSet. The template is replaced when the hash of the
+// desired template differs from the one recorded on the live object or when
+// the live template was edited, the StatefulSet controller then rolls the Pods
+// to the new template.
+func mergeStatefulSet(live, desired *appsv1.StatefulSet) bool {
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	if !reflect.DeepEqual(live.Spec.Replicas, desired.Spec.Replicas) {
+		live.Spec.Replicas = desired.Spec.Replicas
+		changed = true
+	}
+	if !reflect.DeepEqual(live.Spec.UpdateStrategy, desired.Spec.UpdateStrategy) {
+		live.Spec.UpdateStrategy = desired.Spec.UpdateStrategy
+		changed = true
+	}
+	hash := desired.Annotations[specHashAnnotation]
+	if live.Annotations[specHashAnnotation] != hash || !matchesDesired(live.Spec.Template, desired.Spec.Template) {
+		if live.Annotations == nil {
+			live.Annotations = make(map[string]string)
+		}
+		live.Annotations[specHashAnnotation] = hash
+		live.Spec.Template = desired.Spec.Template
+		changed = true
+	}
+	return changed
+}
+
+// mergeIngress updates the annotations and the spec of an Ingress.
+func mergeIngress(live, desired *extv1beta1.Ingress) bool {
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	if !reflect.DeepEqual(live.Annotations, desired.Annotations) {
+		live.Annotations = desired.Annotations
+		changed = true
+	}
+	if !reflect.DeepEqual(live.Spec, desired.Spec) {
+		live.Spec = desired.Spec
+		changed = true
+	}
+	return changed
+}
+
+// mergeRoute updates the spec of a Route. A host name generated by the router
+// is preserved if no host is requested.
+func mergeRoute(live, desired *routev1.Route) bool {
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	spec := desired.Spec.DeepCopy()
+	if len(spec.Host) == 0 {
+		spec.Host = live.Spec.Host
+	}
+	if !reflect.DeepEqual(&live.Spec, spec) {
+		live.Spec = *spec
+		changed = true
+	}
+	return changed
+}
+
+// mergeCronJob replaces the spec of a CronJob when the hash of the desired
+// spec differs from the one recorded on the live object or when the live spec
+// was edited.
+func mergeCronJob(live, desired *batchv1beta1.CronJob) bool {
+	changed := mergeLabels(&live.ObjectMeta, desired.ObjectMeta)
+	hash := desired.Annotations[specHashAnnotation]
+	if live.Annotations[specHashAnnotation] != hash || !matchesDesired(live.Spec, desired.Spec) {
+		if live.Annotations == nil {
+			live.Annotations = make(map[string]string)
+		}
+		live.Annotations[specHashAnnotation] = hash
+		live.Spec = desired.Spec
+		changed = true
+	}
+	return changed
+}
+
+// specHash returns a hash of the JSON representation of the given spec.
+func specHash(spec interface{}) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write(data)
+	return fmt.Sprintf("%x", h.Sum32())
+}
+
+// matchesDesired returns true if every field set in the desired object has the
+// same value in the live object. Fields that are only set in the live object,
+// e.g. defaults filled in by the API server, are ignored, lists must have the
+// same length.
+func matchesDesired(live, desired interface{}) bool {
+	var l, d interface{}
+	if err := roundTrip(live, &l); err != nil {
+		return false
+	}
+	if err := roundTrip(desired, &d); err != nil {
+		return false
+	}
+	return containsFields(l, d)
+}
+
+// roundTrip converts the object to its generic JSON representation.
+func roundTrip(o interface{}, out *interface{}) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// containsFields compares generic JSON values, see matchesDesired.
+func containsFields(live, desired interface{}) bool {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, val := range d {
+			if !containsFields(l[key], val) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return false
+		}
+		for i := range d {
+			if !containsFields(l[i], d[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(live, desired)
+}
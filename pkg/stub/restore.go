@@ -0,0 +1,446 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// restoreAnnotation is set on a Jira resource while it is being restored. It
+// holds the name of the JiraRestore and keeps JIRA scaled down.
+const restoreAnnotation = "app.redhat.com/restore"
+
+// downloadBackupScript downloads the backup named by $BACKUP to the scratch
+// volume.
+const downloadBackupScript = `set -e
+mc config host add backup "$S3_ENDPOINT" "$S3_ACCESS_KEY_ID" "$S3_SECRET_ACCESS_KEY" > /dev/null
+mc cp --recursive "backup/$S3_BUCKET/$S3_PREFIX/$BACKUP/" ` + backupDir + `/`
+
+// restoreHomeScript replaces a home directory with the contents of an
+// archive. The backups directory of the upgrade backups is kept.
+const restoreHomeScript = `set -e
+test -f ` + backupDir + `/%[2]s
+find %[1]s -mindepth 1 -maxdepth 1 ! -name backups -exec rm -rf {} +
+tar xzf ` + backupDir + `/%[2]s -C %[1]s`
+
+// h2RestoreScript recreates the embedded H2 database from the dump.
+const h2RestoreScript = `set -e
+rm -f %[1]s/database/h2db.*
+java -cp "$(ls /opt/atlassian/jira/atlassian-jira/WEB-INF/lib/h2-*.jar | head -n 1)" org.h2.tools.RunScript \
+	-url "jdbc:h2:file:%[1]s/database/h2db" -user sa -script ` + backupDir + `/database.sql`
+
+// handleJiraRestore advances a restore. JIRA is stopped by annotating the
+// Jira resource, a Job restores JIRA Home and the database from the backup
+// and JIRA is started again once the Job has succeeded.
+func handleJiraRestore(r *v1alpha1.JiraRestore) error {
+	log.Debug("handle jira restore")
+	if r.Status.IsFinished() || r.DeletionTimestamp != nil {
+		return nil
+	}
+	restore := r.DeepCopy()
+	restore.SetDefaults()
+	if err := persistRestoreDefaults(r, restore); err != nil {
+		return err
+	}
+	status := r.Status.DeepCopy()
+	if len(status.Phase) == 0 {
+		status.Phase = v1alpha1.JiraRestorePhasePending
+	}
+
+	err := reconcileRestore(restore, status)
+	if serr := updateRestoreStatus(r, status); serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// reconcileRestore advances the restore by one phase where possible.
+func reconcileRestore(r *v1alpha1.JiraRestore, status *v1alpha1.JiraRestoreStatus) error {
+	if len(r.Spec.JiraName) == 0 || len(r.Spec.Backup) == 0 {
+		failRestore(r, nil, status, v1alpha1.JiraRestoreReasonInvalidSpec, "jiraName and backup are required")
+		return nil
+	}
+	j := &v1alpha1.Jira{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Jira",
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.Spec.JiraName,
+			Namespace: r.Namespace,
+		},
+	}
+	if err := getObject(j); errors.IsNotFound(err) {
+		failRestore(r, nil, status, v1alpha1.JiraRestoreReasonJiraNotFound, fmt.Sprintf("jira %s not found", j.Name))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	jira := j.DeepCopy()
+	jira.SetDefaults()
+
+	switch status.Phase {
+	case v1alpha1.JiraRestorePhasePending:
+		if restoreStorage(r, jira) == nil {
+			failRestore(r, j, status, v1alpha1.JiraRestoreReasonNoBackupStorage, "no s3 storage defined in the restore or the jira backup spec")
+			return nil
+		}
+		if !jira.IsPVEnabled() && databaseSpec(jira).Type == v1alpha1.DatabaseTypeH2 {
+			failRestore(r, j, status, v1alpha1.JiraRestoreReasonNoPersistentStorage, "the embedded database cannot be restored without a persistent volume")
+			return nil
+		}
+		if other, ok := j.Annotations[restoreAnnotation]; ok && other != r.Name {
+			status.Message = fmt.Sprintf("waiting for restore %s to finish", other)
+			return nil
+		}
+		if err := setRestoreAnnotation(j, r.Name); err != nil {
+			return err
+		}
+		now := metav1.Now()
+		status.Phase = v1alpha1.JiraRestorePhaseStopping
+		status.Message = fmt.Sprintf("stopping jira %s", j.Name)
+		status.StartTime = &now
+		log.Infof("restoring jira %s/%s from backup %s", j.Namespace, j.Name, r.Spec.Backup)
+		recordEvent(j, v1.EventTypeNormal, "RestoreStarted", fmt.Sprintf("Restoring backup %s", r.Spec.Backup))
+	case v1alpha1.JiraRestorePhaseStopping:
+		ss, err := getJiraStatefulSet(jira)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && (ss.Status.ObservedGeneration < ss.Generation || ss.Status.Replicas > 0) {
+			return nil
+		}
+		if err := createResource(jira, newRestoreJob(r, jira)); err != nil {
+			return err
+		}
+		status.Phase = v1alpha1.JiraRestorePhaseRestoring
+		status.Message = fmt.Sprintf("restoring backup %s", r.Spec.Backup)
+	case v1alpha1.JiraRestorePhaseRestoring:
+		job := newRestoreJob(r, jira)
+		if err := getLiveObject(job); errors.IsNotFound(err) {
+			// The Job was deleted before it finished. It is not recreated as
+			// JIRA Home or the database may be partially restored.
+			failRestore(r, j, status, v1alpha1.JiraRestoreReasonRestoreJobNotFound,
+				fmt.Sprintf("restore job %s was deleted before it finished", job.Name))
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if jobFailed(job) {
+			failRestore(r, j, status, v1alpha1.JiraRestoreReasonRestoreJobFailed, fmt.Sprintf("restore job %s failed", job.Name))
+			return nil
+		}
+		if job.Status.Succeeded == 0 {
+			return nil
+		}
+		if err := setRestoreAnnotation(j, ""); err != nil {
+			return err
+		}
+		status.Phase = v1alpha1.JiraRestorePhaseStarting
+		status.Message = fmt.Sprintf("starting jira %s", j.Name)
+	case v1alpha1.JiraRestorePhaseStarting:
+		ss, err := getJiraStatefulSet(jira)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err != nil || ss.Status.ReadyReplicas == 0 {
+			return nil
+		}
+		now := metav1.Now()
+		status.Phase = v1alpha1.JiraRestorePhaseCompleted
+		status.Message = fmt.Sprintf("restored backup %s", r.Spec.Backup)
+		status.CompletionTime = &now
+		log.Infof("jira %s/%s restored from backup %s", j.Namespace, j.Name, r.Spec.Backup)
+		recordEvent(j, v1.EventTypeNormal, "RestoreCompleted", fmt.Sprintf("Restored backup %s", r.Spec.Backup))
+	}
+	return nil
+}
+
+// failRestore marks the restore as failed and releases the Jira resource, if
+// it was already claimed by the restore.
+func failRestore(r *v1alpha1.JiraRestore, j *v1alpha1.Jira, status *v1alpha1.JiraRestoreStatus, reason, message string) {
+	log.Errorf("restore %s/%s failed: %s", r.Namespace, r.Name, message)
+	now := metav1.Now()
+	status.Phase = v1alpha1.JiraRestorePhaseFailed
+	status.Reason = reason
+	status.Message = message
+	status.CompletionTime = &now
+	if j == nil {
+		return
+	}
+	if j.Annotations[restoreAnnotation] == r.Name {
+		if err := setRestoreAnnotation(j, ""); err != nil {
+			log.Errorf("Failed to release jira %s: %v", j.Name, err)
+		}
+	}
+	recordEvent(j, v1.EventTypeWarning, "RestoreFailed", fmt.Sprintf("Restore %s failed: %s", r.Name, message))
+}
+
+// updateRestoreStatus writes the status back to the JiraRestore if it has
+// changed.
+func updateRestoreStatus(r *v1alpha1.JiraRestore, status *v1alpha1.JiraRestoreStatus) error {
+	if reflect.DeepEqual(&r.Status, status) {
+		return nil
+	}
+	r.Status = *status
+	if err := updateStatusSubresource(r); err != nil {
+		log.Errorf("Failed to update restore status: %v", err)
+		return err
+	}
+	return nil
+}
+
+// setRestoreAnnotation sets the restore annotation of the Jira resource to
+// the given restore name, or removes it if the name is empty.
+func setRestoreAnnotation(j *v1alpha1.Jira, name string) error {
+	if j.Annotations[restoreAnnotation] == name {
+		return nil
+	}
+	if len(name) == 0 {
+		delete(j.Annotations, restoreAnnotation)
+	} else {
+		if j.Annotations == nil {
+			j.Annotations = make(map[string]string)
+		}
+		j.Annotations[restoreAnnotation] = name
+	}
+	if err := updateObject(j); err != nil {
+		log.Errorf("Failed to update jira: %v", err)
+		return err
+	}
+	return nil
+}
+
+// releaseRestore removes the restore annotation from the Jira resource and
+// its defaulted copy if the JiraRestore named by it is gone, being deleted or
+// finished, so that a deleted restore does not keep JIRA stopped.
+func releaseRestore(j, jira *v1alpha1.Jira) error {
+	name, ok := j.Annotations[restoreAnnotation]
+	if !ok {
+		return nil
+	}
+	r := &v1alpha1.JiraRestore{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "JiraRestore",
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: j.Namespace,
+		},
+	}
+	err := getLiveObject(r)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && r.DeletionTimestamp == nil && !r.Status.IsFinished() {
+		return nil
+	}
+	log.Infof("releasing jira %s/%s from restore %s", j.Namespace, j.Name, name)
+	if err := setRestoreAnnotation(j, ""); err != nil {
+		return err
+	}
+	delete(jira.Annotations, restoreAnnotation)
+	return nil
+}
+
+// isRestoring returns true if the Jira resource is being restored.
+func isRestoring(j *v1alpha1.Jira) bool {
+	_, ok := j.Annotations[restoreAnnotation]
+	return ok
+}
+
+// restoreStorage returns the object storage the backup is downloaded from.
+func restoreStorage(r *v1alpha1.JiraRestore, j *v1alpha1.Jira) *v1alpha1.JiraBackupS3Spec {
+	if r.Spec.S3 != nil {
+		s3 := r.Spec.S3.DeepCopy()
+		if len(s3.Prefix) == 0 {
+			s3.Prefix = j.Namespace + "/" + j.Name
+		}
+		return s3
+	}
+	if j.Spec.Backup != nil {
+		return &j.Spec.Backup.S3
+	}
+	return nil
+}
+
+// newRestoreJob returns the Job that restores JIRA Home, the shared home and
+// the database. The backup is downloaded and the home directories are
+// replaced by init containers, the database is restored by the main
+// container.
+func newRestoreJob(r *v1alpha1.JiraRestore, j *v1alpha1.Jira) *batchv1.Job {
+	s3 := restoreStorage(r, j)
+	scratch := v1.VolumeMount{
+		Name:      "backup",
+		MountPath: backupDir,
+	}
+	spec := v1.PodSpec{
+		RestartPolicy: v1.RestartPolicyOnFailure,
+		Volumes: []v1.Volume{{
+			Name: "backup",
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		}},
+		InitContainers: []v1.Container{{
+			Name:    "download",
+			Image:   r.Spec.Image,
+			Command: []string{"/bin/sh", "-c", downloadBackupScript},
+			Env: []v1.EnvVar{
+				{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+				{Name: "S3_BUCKET", Value: s3.Bucket},
+				{Name: "S3_PREFIX", Value: s3.Prefix},
+				{Name: "BACKUP", Value: r.Spec.Backup},
+				secretEnvVar("S3_ACCESS_KEY_ID", s3.CredentialsSecret, SecretKeyS3AccessKeyID),
+				secretEnvVar("S3_SECRET_ACCESS_KEY", s3.CredentialsSecret, SecretKeyS3SecretAccessKey),
+			},
+			VolumeMounts: []v1.VolumeMount{scratch},
+		}},
+	}
+
+	mp := j.Spec.DataMountPath
+	data := v1.VolumeMount{
+		Name:      "jira-data",
+		MountPath: mp,
+	}
+	if j.IsPVEnabled() {
+		spec.Volumes = append(spec.Volumes, v1.Volume{
+			Name: "jira-data",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+					ClaimName: dataClaimName(j, 0),
+				},
+			},
+		})
+		spec.InitContainers = append(spec.InitContainers, v1.Container{
+			Name:         "restore-home",
+			Image:        "busybox",
+			Command:      []string{"/bin/sh", "-c", fmt.Sprintf(restoreHomeScript, mp, "jira-home.tar.gz")},
+			VolumeMounts: []v1.VolumeMount{scratch, data},
+		})
+	}
+	if hasSharedHomeClaim(j) {
+		sp := j.Spec.Storage.SharedHome.MountPath
+		spec.Volumes = append(spec.Volumes, v1.Volume{
+			Name: "jira-shared-home",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+					ClaimName: sharedHomeClaimName(j),
+				},
+			},
+		})
+		spec.InitContainers = append(spec.InitContainers, v1.Container{
+			Name:    "restore-shared-home",
+			Image:   "busybox",
+			Command: []string{"/bin/sh", "-c", fmt.Sprintf(restoreHomeScript, sp, "jira-shared-home.tar.gz")},
+			VolumeMounts: []v1.VolumeMount{scratch, {
+				Name:      "jira-shared-home",
+				MountPath: sp,
+			}},
+		})
+	}
+
+	c := databaseRestoreContainer(r, j)
+	c.VolumeMounts = []v1.VolumeMount{scratch}
+	if j.IsPVEnabled() {
+		c.VolumeMounts = append(c.VolumeMounts, data)
+	}
+	spec.Containers = []v1.Container{c}
+
+	backoffLimit := int32(2)
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-restore", r.Name),
+			Namespace:       r.Namespace,
+			OwnerReferences: restoreOwnerRef(r),
+			Labels:          restoreLabels(j),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: restoreLabels(j),
+				},
+				Spec: spec,
+			},
+		},
+	}
+}
+
+// databaseRestoreContainer returns the container that restores the database
+// dump. Nothing is restored if the backup contains no database dump.
+func databaseRestoreContainer(r *v1alpha1.JiraRestore, j *v1alpha1.Jira) v1.Container {
+	db := databaseSpec(j)
+	c := v1.Container{
+		Name:  "restore-database",
+		Image: r.Spec.RestoreImage,
+	}
+	if len(c.Image) == 0 && j.Spec.Backup != nil {
+		c.Image = j.Spec.Backup.DumpImage
+	}
+	if len(c.Image) == 0 {
+		c.Image = defaultDumpImage(j)
+	}
+	dump := backupDir + "/database.sql"
+	skip := fmt.Sprintf("test -f %s || exit 0; ", dump)
+	switch db.Type {
+	case v1alpha1.DatabaseTypeH2:
+		c.Command = []string{"/bin/sh", "-c", skip + fmt.Sprintf(h2RestoreScript, j.Spec.DataMountPath)}
+	case v1alpha1.DatabaseTypePostgres:
+		c.Command = []string{"/bin/sh", "-c", skip + "psql -v ON_ERROR_STOP=1 -f " + dump}
+		c.Env = postgresEnv(db)
+	case v1alpha1.DatabaseTypeMySQL:
+		c.Command = []string{"/bin/sh", "-c", skip + `mysql -h "$DB_HOST" -P "$DB_PORT" -u "$DB_USERNAME" "$DB_NAME" < ` + dump}
+		c.Env = mysqlEnv(db)
+	default:
+		c.Image = "busybox"
+		c.Command = []string{"/bin/sh", "-c", fmt.Sprintf("echo database type %s is not restored", db.Type)}
+	}
+	return c
+}
+
+// restoreLabels returns the labels of the restore Job and Pod.
+func restoreLabels(j *v1alpha1.Jira) map[string]string {
+	return map[string]string{
+		"app":     "jira-restore",
+		"cluster": j.Name,
+	}
+}
+
+// restoreOwnerRef returns an owner reference to the JiraRestore.
+func restoreOwnerRef(r *v1alpha1.JiraRestore) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(r, schema.GroupVersionKind{
+			Group:   v1alpha1.SchemeGroupVersion.Group,
+			Version: v1alpha1.SchemeGroupVersion.Version,
+			Kind:    "JiraRestore",
+		}),
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// inServiceLabel selects the cluster nodes that receive traffic from the JIRA
+// Service. A node is drained by setting the label to false.
+const inServiceLabel = "app.redhat.com/in-service"
+
+// errNoAdminCredentials is returned by indexCaughtUp if the JIRA Secret has no
+// administrator credentials to read the index summary with.
+var errNoAdminCredentials = fmt.Errorf("the JIRA secret has no administrator credentials")
+
+// isRollingRestart returns true if the nodes are restarted one at a time by
+// the operator instead of the StatefulSet controller.
+func isRollingRestart(j *v1alpha1.Jira) bool {
+	return j.IsClustered() && replicas(j) > 1 && !isScaledDown(j)
+}
+
+// jiraUpdateStrategy returns the update strategy of the JIRA StatefulSet. The
+// partition holds back the nodes that have not been restarted yet by a
+// rolling restart.
+func jiraUpdateStrategy(j *v1alpha1.Jira) appsv1.StatefulSetUpdateStrategy {
+	partition := int32(0)
+	if r := j.Status.Rollout; r != nil {
+		partition = r.Partition
+	}
+	return appsv1.StatefulSetUpdateStrategy{
+		Type: appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+			Partition: &partition,
+		},
+	}
+}
+
+// reconcileRollout advances the rolling restart of the cluster nodes. When
+// the pod template changes, the new template is applied with a partition
+// that holds back all nodes. Each node is then drained from the Service,
+// restarted by lowering the partition and waited for until JIRA is running
+// and its index has caught up, before the next node is restarted. The index
+// check is skipped without administrator credentials. A node that does not
+// come back or whose index cannot be checked within the startup time of the
+// probes pauses the rollout with the RolloutPaused condition.
+func reconcileRollout(j *v1alpha1.Jira) error {
+	if !isRollingRestart(j) {
+		j.Status.Rollout = nil
+		if j.Status.GetCondition(v1alpha1.JiraConditionRolloutPaused) != nil {
+			j.Status.SetCondition(v1alpha1.JiraConditionRolloutPaused, v1.ConditionFalse, "NoRollout", "")
+		}
+		return nil
+	}
+	ss, err := getJiraStatefulSet(j)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	revision := specHash(jiraPodTemplate(j))
+	r := j.Status.Rollout
+	if r == nil || r.Revision != revision {
+		// Stopped nodes are started with the new template right away.
+		if r == nil && (ss.Annotations[specHashAnnotation] == revision || ss.Status.Replicas == 0) {
+			return nil
+		}
+		// A node drained by a superseded rollout is put back into service.
+		if r != nil && r.Phase == v1alpha1.JiraRolloutPhaseDraining {
+			if err := setInService(j, r.Node, true); err != nil {
+				return err
+			}
+		}
+		log.Infof("starting rolling restart of jira %s/%s", j.Namespace, j.Name)
+		recordEvent(j, v1.EventTypeNormal, "RolloutStarted", "Restarting cluster nodes one at a time")
+		j.Status.Rollout = &v1alpha1.JiraRolloutStatus{
+			Revision:       revision,
+			Partition:      replicas(j),
+			PhaseStartTime: metav1.Now(),
+		}
+		j.Status.SetCondition(v1alpha1.JiraConditionRolloutPaused, v1.ConditionFalse, "Progressing", "")
+		return nil
+	}
+	if ss.Status.ObservedGeneration < ss.Generation {
+		return nil
+	}
+	if n := replicas(j); r.Partition > n {
+		r.Partition = n
+	}
+
+	switch r.Phase {
+	case v1alpha1.JiraRolloutPhaseRestarting:
+		pod, err := getJiraPod(j, r.Node)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		pauseReason := "NodeNotRunning"
+		ok, reason := nodeRunning(j, pod, ss.Status.UpdateRevision)
+		if ok {
+			caughtUp, err := indexCaughtUp(j, nodeURL(j, pod))
+			switch {
+			case err == errNoAdminCredentials:
+				// The index cannot be checked at all, the node is only
+				// waited for until it is running.
+				recordEvent(j, v1.EventTypeWarning, "IndexCheckSkipped",
+					fmt.Sprintf("Restarted node %s without checking its index: %v", r.Node, err))
+			case err != nil:
+				pauseReason = "IndexCheckFailed"
+				ok, reason = false, fmt.Sprintf("unable to check the index: %v", err)
+			default:
+				ok, reason = caughtUp, "index replication has not caught up"
+			}
+		}
+		if !ok {
+			timeout := time.Duration(j.Spec.Probes.StartupSeconds) * time.Second
+			if time.Since(r.PhaseStartTime.Time) > timeout {
+				pauseRollout(j, pauseReason, fmt.Sprintf("node %s did not come back: %s", r.Node, reason))
+			}
+			return nil
+		}
+		j.Status.SetCondition(v1alpha1.JiraConditionRolloutPaused, v1.ConditionFalse, "Progressing",
+			fmt.Sprintf("node %s restarted", r.Node))
+		if r.Partition == 0 {
+			log.Infof("rolling restart of jira %s/%s completed", j.Namespace, j.Name)
+			recordEvent(j, v1.EventTypeNormal, "RolloutCompleted", "All cluster nodes restarted")
+			j.Status.Rollout = nil
+			return nil
+		}
+		r.Phase = ""
+		fallthrough
+	case "":
+		r.Node = fmt.Sprintf("%s-%d", j.Name, r.Partition-1)
+		r.Phase = v1alpha1.JiraRolloutPhaseDraining
+		r.PhaseStartTime = metav1.Now()
+		log.Infof("draining node %s", r.Node)
+		return setInService(j, r.Node, false)
+	case v1alpha1.JiraRolloutPhaseDraining:
+		drain := time.Duration(j.Spec.Cluster.DrainSeconds) * time.Second
+		if time.Since(r.PhaseStartTime.Time) < drain {
+			return nil
+		}
+		log.Infof("restarting node %s", r.Node)
+		r.Partition--
+		r.Phase = v1alpha1.JiraRolloutPhaseRestarting
+		r.PhaseStartTime = metav1.Now()
+	}
+	return nil
+}
+
+// getJiraPod returns the JIRA Pod with the given name.
+func getJiraPod(j *v1alpha1.Jira, name string) (*v1.Pod, error) {
+	pod := &v1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: j.Namespace,
+		},
+	}
+	err := getObject(pod)
+	return pod, err
+}
+
+// setInService adds the node to or removes it from the JIRA Service.
+func setInService(j *v1alpha1.Jira, name string, inService bool) error {
+	pod, err := getJiraPod(j, name)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	val := fmt.Sprintf("%t", inService)
+	if pod.Labels[inServiceLabel] == val {
+		return nil
+	}
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[inServiceLabel] = val
+	if err := updateObject(pod); err != nil {
+		log.Errorf("Failed to update pod %s: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+// pauseRollout pauses the rolling restart with the RolloutPaused condition.
+func pauseRollout(j *v1alpha1.Jira, reason, msg string) {
+	if !j.Status.IsConditionTrue(v1alpha1.JiraConditionRolloutPaused) {
+		log.Errorf("rolling restart of jira %s/%s paused: %s", j.Namespace, j.Name, msg)
+		recordEvent(j, v1.EventTypeWarning, "RolloutPaused", msg)
+	}
+	j.Status.SetCondition(v1alpha1.JiraConditionRolloutPaused, v1.ConditionTrue, reason, msg)
+}
+
+// nodeURL returns the base URL of JIRA on the node.
+func nodeURL(j *v1alpha1.Jira, pod *v1.Pod) string {
+	return fmt.Sprintf("http://%s:8080%s", pod.Status.PodIP, contextPath(j))
+}
+
+// nodeRunning returns true if the node runs the given revision, is ready and
+// reports the RUNNING state. Otherwise the reason is returned.
+func nodeRunning(j *v1alpha1.Jira, pod *v1.Pod, revision string) (bool, string) {
+	if pod == nil || len(pod.Status.PodIP) == 0 {
+		return false, "pod is not running"
+	}
+	if pod.Labels[appsv1.StatefulSetRevisionLabel] != revision {
+		return false, "pod has not been updated"
+	}
+	ready := false
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady && c.Status == v1.ConditionTrue {
+			ready = true
+		}
+	}
+	if !ready {
+		return false, "pod is not ready"
+	}
+	state, err := jiraStateAt(nodeURL(j, pod))
+	if err != nil {
+		return false, err.Error()
+	}
+	if state != "RUNNING" {
+		return false, fmt.Sprintf("jira is %s", state)
+	}
+	return true, ""
+}
+
+// indexCaughtUp returns true if the issue index of the node contains all
+// issues of the database according to the index summary of JIRA. The summary
+// requires the administrator credentials of the JIRA Secret,
+// errNoAdminCredentials is returned without them.
+func indexCaughtUp(j *v1alpha1.Jira, base string) (bool, error) {
+	secret := &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      j.Spec.SecretName,
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(secret); errors.IsNotFound(err) {
+		return false, errNoAdminCredentials
+	} else if err != nil {
+		return false, err
+	}
+	user, password := secret.Data[SecretKeyAdminUsername], secret.Data[SecretKeyAdminPassword]
+	if len(user) == 0 || len(password) == 0 {
+		return false, errNoAdminCredentials
+	}
+
+	req, err := http.NewRequest(http.MethodGet, base+"/rest/api/2/index/summary", nil)
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(string(user), string(password))
+	resp, err := statusClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("index summary returned %s", resp.Status)
+	}
+
+	var summary struct {
+		IssueIndex struct {
+			IndexReadable   bool  `json:"indexReadable"`
+			CountInDatabase int64 `json:"countInDatabase"`
+			CountInIndex    int64 `json:"countInIndex"`
+		} `json:"issueIndex"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return false, err
+	}
+	idx := summary.IssueIndex
+	return idx.IndexReadable && idx.CountInIndex >= idx.CountInDatabase, nil
+}
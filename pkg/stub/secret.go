@@ -0,0 +1,168 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"fmt"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SecretKeyDatabaseUsername is the Secret key for the database user.
+	SecretKeyDatabaseUsername = "username"
+	// SecretKeyDatabasePassword is the Secret key for the database password.
+	SecretKeyDatabasePassword = "password"
+	// SecretKeyLicense is the Secret key for the JIRA license.
+	SecretKeyLicense = "license"
+	// SecretKeyAdminUsername is the Secret key for a JIRA administrator. The
+	// operator uses the credentials to query the REST API of JIRA.
+	SecretKeyAdminUsername = "admin-username"
+	// SecretKeyAdminPassword is the Secret key for the JIRA administrator password.
+	SecretKeyAdminPassword = "admin-password"
+
+	// secretMountPath is the directory the license and the administrator
+	// credentials are mounted to in the JIRA container.
+	secretMountPath = "/var/run/secrets/jira"
+
+	// dbUsernamePlaceholder is replaced with the database user at pod start.
+	dbUsernamePlaceholder = "@@DB_USERNAME@@"
+	// dbPasswordPlaceholder is replaced with the database password at pod start.
+	dbPasswordPlaceholder = "@@DB_PASSWORD@@"
+)
+
+// renderDatabaseConfigScript renders the dbconfig.xml template from the
+// ConfigMap into JIRA Home, replacing the credential placeholders with the
+// XML escaped values of the DB_USERNAME and DB_PASSWORD environment variables.
+const renderDatabaseConfigScript = `awk '
+function esc(v) {
+	gsub(/&/, "\\&amp;", v); gsub(/</, "\\&lt;", v); gsub(/>/, "\\&gt;", v); gsub(/"/, "\\&quot;", v)
+	return v
+}
+function rep(s, k, v,    out, i) {
+	out = ""
+	while ((i = index(s, k)) > 0) {
+		out = out substr(s, 1, i - 1) v
+		s = substr(s, i + length(k))
+	}
+	return out s
+}
+{ print rep(rep($0, "` + dbUsernamePlaceholder + `", esc(ENVIRON["DB_USERNAME"])), "` + dbPasswordPlaceholder + `", esc(ENVIRON["DB_PASSWORD"])) }
+' /etc/jira/dbconfig.xml > %s/dbconfig.xml`
+
+// validateDatabaseSecret checks that the Secret with the database credentials
+// contains a username and password. The values are never read by the operator.
+func validateDatabaseSecret(j *v1alpha1.Jira) error {
+	db := databaseSpec(j)
+	if db.Type == v1alpha1.DatabaseTypeH2 {
+		return nil
+	}
+	if len(db.CredentialsSecret) == 0 {
+		return invalidSpec(fmt.Errorf("no credentials secret defined for %s database", db.Type))
+	}
+	secret := &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      db.CredentialsSecret,
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(secret); err != nil {
+		if errors.IsNotFound(err) {
+			return invalidSpec(fmt.Errorf("failed to get database credentials: %v", err))
+		}
+		return fmt.Errorf("failed to get database credentials: %v", err)
+	}
+	for _, key := range []string{SecretKeyDatabaseUsername, SecretKeyDatabasePassword} {
+		if _, ok := secret.Data[key]; !ok {
+			return invalidSpec(fmt.Errorf("secret %s is missing key %s", secret.Name, key))
+		}
+	}
+	return nil
+}
+
+// secretEnvVar returns an environment variable sourced from an optional
+// Secret key.
+func secretEnvVar(name, secretName, key string) v1.EnvVar {
+	optional := true
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: secretName,
+				},
+				Key:      key,
+				Optional: &optional,
+			},
+		},
+	}
+}
+
+// secretVolume returns an optional volume with the license and the
+// administrator credentials from the JIRA Secret. Missing keys are left out,
+// so the files only exist for the keys set in the Secret.
+func secretVolume(j *v1alpha1.Jira) v1.Volume {
+	optional := true
+	items := make([]v1.KeyToPath, 0)
+	for _, key := range []string{SecretKeyLicense, SecretKeyAdminUsername, SecretKeyAdminPassword} {
+		items = append(items, v1.KeyToPath{Key: key, Path: key})
+	}
+	return v1.Volume{
+		Name: "jira-secret",
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{
+				SecretName: j.Spec.SecretName,
+				Items:      items,
+				Optional:   &optional,
+			},
+		},
+	}
+}
+
+// secretVolumeMount returns the read-only mount of the JIRA Secret volume.
+func secretVolumeMount() v1.VolumeMount {
+	return v1.VolumeMount{
+		Name:      "jira-secret",
+		MountPath: secretMountPath,
+		ReadOnly:  true,
+	}
+}
+
+// initEnv returns the environment of the init container with the database
+// credentials used to render dbconfig.xml.
+func initEnv(j *v1alpha1.Jira) []v1.EnvVar {
+	db := databaseSpec(j)
+	if db.Type == v1alpha1.DatabaseTypeH2 {
+		return nil
+	}
+	return []v1.EnvVar{
+		secretEnvVar("DB_USERNAME", db.CredentialsSecret, SecretKeyDatabaseUsername),
+		secretEnvVar("DB_PASSWORD", db.CredentialsSecret, SecretKeyDatabasePassword),
+	}
+}
+
+// jiraEnv returns the environment of the JIRA container with the reverse
+// proxy and monitoring settings.
+func jiraEnv(j *v1alpha1.Jira) []v1.EnvVar {
+	return append(proxyEnv(j), monitoringEnv(j)...)
+}
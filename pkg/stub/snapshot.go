@@ -0,0 +1,279 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	snapshotv1alpha1 "github.com/jmckind/jira-operator/pkg/apis/snapshot/v1alpha1"
+	"github.com/jmckind/jira-operator/pkg/schedule"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// snapshotBackupLabel marks VolumeSnapshots taken by scheduled backups.
+	// Only these snapshots are pruned.
+	snapshotBackupLabel = "app.redhat.com/backup"
+	// snapshotVersionLabel records the JIRA version of a snapshot.
+	snapshotVersionLabel = "app.redhat.com/jira-version"
+)
+
+// isSnapshotBackupEnabled returns true if scheduled backups are taken as
+// VolumeSnapshots. A cluster is never backed up with snapshots, as they do not
+// cover the shared home; the webhook rejects the Snapshot mode for a cluster.
+func isSnapshotBackupEnabled(j *v1alpha1.Jira) bool {
+	return j.Spec.Backup != nil && j.Spec.Backup.Mode == v1alpha1.JiraBackupModeSnapshot && j.IsPVEnabled() && !j.IsClustered()
+}
+
+// isQuiesced returns true if JIRA must be stopped for a snapshot backup.
+func isQuiesced(j *v1alpha1.Jira) bool {
+	return j.Status.Backup != nil && j.Status.Backup.Snapshot != nil
+}
+
+// reconcileSnapshotBackup advances the scheduled snapshot backup of the JIRA
+// Home PVC. When a backup is due JIRA is stopped, a VolumeSnapshot of the PVC
+// is taken and JIRA is started again as soon as the snapshot has been cut.
+// Old snapshots beyond the retention are pruned.
+func reconcileSnapshotBackup(j *v1alpha1.Jira) error {
+	if !isSnapshotBackupEnabled(j) {
+		return nil
+	}
+	b := j.Spec.Backup
+	if j.Status.Backup == nil {
+		j.Status.Backup = &v1alpha1.JiraBackupStatus{}
+	}
+	bs := j.Status.Backup
+
+	if bs.Snapshot == nil {
+		if b.Suspend || (j.Status.Upgrade != nil && !j.Status.Upgrade.IsFinished()) || isRestoring(j) {
+			return nil
+		}
+		sched, err := schedule.Parse(b.Schedule)
+		if err != nil {
+			recordEvent(j, v1.EventTypeWarning, "InvalidSchedule", err.Error())
+			return nil
+		}
+		since := j.CreationTimestamp.Time
+		if bs.LastScheduleTime != nil {
+			since = bs.LastScheduleTime.Time
+		}
+		now := time.Now()
+		if sched.LastMissed(since, now) == nil {
+			return nil
+		}
+		start := metav1.NewTime(now)
+		bs.LastScheduleTime = &start
+		bs.Snapshot = &v1alpha1.JiraSnapshotBackupStatus{
+			Name:      fmt.Sprintf("%s-%s", j.Name, now.UTC().Format("20060102150405")),
+			Phase:     v1alpha1.JiraSnapshotBackupPhaseQuiescing,
+			StartTime: start,
+		}
+		log.Infof("starting snapshot backup %s of jira %s/%s", bs.Snapshot.Name, j.Namespace, j.Name)
+	}
+
+	switch bs.Snapshot.Phase {
+	case v1alpha1.JiraSnapshotBackupPhaseQuiescing:
+		ss, err := getJiraStatefulSet(j)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && (ss.Status.ObservedGeneration < ss.Generation || ss.Status.Replicas > 0) {
+			return nil
+		}
+		if err := createObject(newBackupSnapshot(j, bs.Snapshot.Name)); err != nil && !errors.IsAlreadyExists(err) {
+			failSnapshotBackup(j, fmt.Sprintf("failed to create snapshot %s: %v", bs.Snapshot.Name, err))
+			return nil
+		}
+		bs.Snapshot.Phase = v1alpha1.JiraSnapshotBackupPhaseSnapshotting
+	case v1alpha1.JiraSnapshotBackupPhaseSnapshotting:
+		snap := newBackupSnapshot(j, bs.Snapshot.Name)
+		if err := getLiveObject(snap); errors.IsNotFound(err) {
+			failSnapshotBackup(j, fmt.Sprintf("snapshot %s was deleted", snap.Name))
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if e := snap.Status.Error; e != nil {
+			failSnapshotBackup(j, fmt.Sprintf("snapshot %s failed: %s", snap.Name, e.Message))
+			return nil
+		}
+		if snap.Status.CreationTime == nil {
+			return nil
+		}
+		now := metav1.Now()
+		bs.LastSuccessTime = &now
+		bs.Snapshot = nil
+		log.Infof("snapshot backup %s of jira %s/%s completed", snap.Name, j.Namespace, j.Name)
+		recordEvent(j, v1.EventTypeNormal, "BackupSucceeded", fmt.Sprintf("Snapshot %s taken", snap.Name))
+		return pruneSnapshots(j)
+	}
+	return nil
+}
+
+// failSnapshotBackup records a failed snapshot backup, which starts JIRA
+// again.
+func failSnapshotBackup(j *v1alpha1.Jira, message string) {
+	log.Errorf("snapshot backup of jira %s/%s failed: %s", j.Namespace, j.Name, message)
+	now := metav1.Now()
+	j.Status.Backup.LastFailureTime = &now
+	j.Status.Backup.Snapshot = nil
+	recordEvent(j, v1.EventTypeWarning, "BackupFailed", message)
+}
+
+// snapshotBackupLabels returns the labels of the VolumeSnapshots taken by
+// scheduled backups.
+func snapshotBackupLabels(j *v1alpha1.Jira) map[string]string {
+	l := defaultLabels(j)
+	l[snapshotBackupLabel] = "scheduled"
+	return l
+}
+
+// newBackupSnapshot returns a VolumeSnapshot of the JIRA Home PVC. The
+// snapshot has no owner so that it outlives the Jira resource.
+func newBackupSnapshot(j *v1alpha1.Jira, name string) *snapshotv1alpha1.VolumeSnapshot {
+	l := jiraLabels(j)
+	for key, val := range snapshotBackupLabels(j) {
+		l[key] = val
+	}
+	l[snapshotVersionLabel] = j.Spec.BaseImageVersion
+	snap := &snapshotv1alpha1.VolumeSnapshot{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "VolumeSnapshot",
+			APIVersion: "snapshot.storage.k8s.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: j.Namespace,
+			Labels:    l,
+		},
+		Spec: snapshotv1alpha1.VolumeSnapshotSpec{
+			Source: &snapshotv1alpha1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: dataClaimName(j, 0),
+			},
+		},
+	}
+	if sc := j.Spec.Storage.SnapshotClassName; len(sc) > 0 {
+		snap.Spec.VolumeSnapshotClassName = &sc
+	}
+	return snap
+}
+
+// pruneSnapshots deletes the oldest snapshots taken by scheduled backups
+// beyond the retention.
+func pruneSnapshots(j *v1alpha1.Jira) error {
+	snaps := &snapshotv1alpha1.VolumeSnapshotList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "VolumeSnapshot",
+			APIVersion: "snapshot.storage.k8s.io/v1alpha1",
+		},
+	}
+	if err := listObjects(j.Namespace, snapshotBackupLabels(j), snaps); err != nil {
+		log.Errorf("Failed to list snapshots: %v", err)
+		return err
+	}
+	items := snaps.Items
+	sort.Slice(items, func(a, b int) bool {
+		return items[b].CreationTimestamp.Before(&items[a].CreationTimestamp)
+	})
+	for i := int(j.Spec.Backup.Retention); i < len(items); i++ {
+		snap := &items[i]
+		snap.TypeMeta = snaps.TypeMeta
+		log.Infof("pruning snapshot %s/%s", snap.Namespace, snap.Name)
+		if err := deleteObject(snap); err != nil && !errors.IsNotFound(err) {
+			log.Errorf("Failed to delete snapshot: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// dataSourceClaim is a PersistentVolumeClaim with a dataSource, which is not
+// part of the vendored core API.
+type dataSourceClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              dataSourceClaimSpec `json:"spec"`
+}
+
+// dataSourceClaimSpec is a PersistentVolumeClaimSpec with a dataSource.
+type dataSourceClaimSpec struct {
+	v1.PersistentVolumeClaimSpec `json:",inline"`
+	DataSource                   *snapshotv1alpha1.TypedLocalObjectReference `json:"dataSource,omitempty"`
+}
+
+// newJiraDataClaim will create the JIRA Home PVC of the StatefulSet from the
+// VolumeSnapshot named by the data source before the StatefulSet creates an
+// empty PVC. An existing PVC is never modified.
+func newJiraDataClaim(j *v1alpha1.Jira) error {
+	if !j.IsPVEnabled() || j.Spec.Storage.DataSource == nil {
+		return nil
+	}
+	pvc := &v1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dataClaimName(j, 0),
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(pvc); err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		log.Errorf("Failed to get persistent volume claim: %v", err)
+		return err
+	}
+
+	group := snapshotv1alpha1.SchemeGroupVersion.Group
+	claim := &dataSourceClaim{
+		TypeMeta:   pvc.TypeMeta,
+		ObjectMeta: pvc.ObjectMeta,
+		Spec: dataSourceClaimSpec{
+			PersistentVolumeClaimSpec: *j.LocalHomeClaimSpec(),
+			DataSource: &snapshotv1alpha1.TypedLocalObjectReference{
+				APIGroup: &group,
+				Kind:     "VolumeSnapshot",
+				Name:     j.Spec.Storage.DataSource.VolumeSnapshotName,
+			},
+		},
+	}
+	claim.Labels = jiraLabels(j)
+	body, err := json.Marshal(claim)
+	if err != nil {
+		return err
+	}
+	log.Infof("creating persistent volume claim %s/%s from snapshot %s", pvc.Namespace, pvc.Name, claim.Spec.DataSource.Name)
+	err = kubeClientset.CoreV1().RESTClient().Post().
+		Namespace(j.Namespace).
+		Resource("persistentvolumeclaims").
+		Body(body).
+		Do().
+		Error()
+	if err != nil && !errors.IsAlreadyExists(err) {
+		log.Errorf("Failed to create persistent volume claim: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// updateStatus observes the resources of the JIRA deployment described by
+// the defaulted resource and writes the resulting status back to the original
+// resource. The resource is only updated when the status has changed.
+func updateStatus(orig *v1alpha1.Jira, j *v1alpha1.Jira, reconcileErr error) error {
+	status := j.Status.DeepCopy()
+	status.ObservedGeneration = orig.Generation
+	status.Endpoint = serviceEndpoint(j)
+	status.Selector = labels.SelectorFromSet(defaultLabels(j)).String()
+	status.URL = ""
+	if u := externalURL(j); u != nil {
+		status.URL = u.String()
+	}
+
+	ready := true
+	ready = observeConfigMap(j, status) && ready
+	ready = observeStorage(j, status) && ready
+	podReady, observed := observePod(j, status)
+	ready = podReady && ready
+	ready = observeHealth(j, status) && ready
+	ready = observeService(j, status) && ready
+	observeBackup(j, status)
+
+	observeReconcile(status, reconcileErr)
+
+	switch {
+	case j.DeletionTimestamp != nil:
+		status.Phase = v1alpha1.JiraPhaseTerminating
+	case isInvalidSpec(reconcileErr):
+		status.Phase = v1alpha1.JiraPhaseFailed
+	case ready:
+		status.Phase = v1alpha1.JiraPhaseRunning
+	case observed:
+		status.Phase = v1alpha1.JiraPhaseCreating
+	default:
+		status.Phase = v1alpha1.JiraPhasePending
+	}
+
+	if reflect.DeepEqual(&orig.Status, status) {
+		return nil
+	}
+	log.Debugf("updating status for %s/%s: %s", orig.Namespace, orig.Name, status.Phase)
+	orig.Status = *status
+	if err := updateStatusSubresource(orig); err != nil {
+		log.Errorf("Failed to update status: %v", err)
+		return err
+	}
+	return nil
+}
+
+// updateStatusSubresource writes the status of a custom resource through the
+// status subresource and updates the object with the result. API servers
+// without the status subresource store the status with the resource, so the
+// resource is updated instead.
+func updateStatusSubresource(o runtime.Object) error {
+	err := kubeClient.Status().Update(context.TODO(), o)
+	if errors.IsNotFound(err) {
+		return updateObject(o)
+	}
+	return err
+}
+
+// observeConfigMap sets the ConfigMapReady condition.
+func observeConfigMap(j *v1alpha1.Jira, status *v1alpha1.JiraStatus) bool {
+	cm := &v1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      j.Spec.ConfigMapName,
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(cm); err != nil {
+		setConditionFromError(status, v1alpha1.JiraConditionConfigMapReady, err)
+		return false
+	}
+	status.SetCondition(v1alpha1.JiraConditionConfigMapReady, v1.ConditionTrue, "Found", "")
+	return true
+}
+
+// observeStorage sets the StorageBound condition.
+func observeStorage(j *v1alpha1.Jira, status *v1alpha1.JiraStatus) bool {
+	if !j.IsPVEnabled() {
+		status.SetCondition(v1alpha1.JiraConditionStorageBound, v1.ConditionTrue, "EmptyDir", "no persistent volume claim requested")
+		return true
+	}
+	pvc := &v1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dataClaimName(j, 0),
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(pvc); err != nil {
+		setConditionFromError(status, v1alpha1.JiraConditionStorageBound, err)
+		return false
+	}
+	if pvc.Status.Phase != v1.ClaimBound {
+		status.SetCondition(v1alpha1.JiraConditionStorageBound, v1.ConditionFalse, string(pvc.Status.Phase),
+			fmt.Sprintf("persistent volume claim %s is %s", pvc.Name, pvc.Status.Phase))
+		return false
+	}
+	status.SetCondition(v1alpha1.JiraConditionStorageBound, v1.ConditionTrue, string(pvc.Status.Phase), "")
+	return true
+}
+
+// observePod sets the PodReady condition and the running image from the
+// JIRA StatefulSet. It returns whether the pods are ready, and whether the
+// StatefulSet exists and has been observed by the StatefulSet controller,
+// which tells a pending deployment from one whose pods are being created. A
+// StatefulSet scaled to zero is never ready.
+func observePod(j *v1alpha1.Jira, status *v1alpha1.JiraStatus) (ready, observed bool) {
+	ss := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      j.Name,
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(ss); err != nil {
+		setConditionFromError(status, v1alpha1.JiraConditionPodReady, err)
+		return false, false
+	}
+	for _, c := range ss.Spec.Template.Spec.Containers {
+		if c.Name == "jira" {
+			status.Image = c.Image
+		}
+	}
+	status.Replicas = ss.Status.Replicas
+	status.ReadyReplicas = ss.Status.ReadyReplicas
+	observed = ss.Status.ObservedGeneration > 0
+	replicas := int32(1)
+	if ss.Spec.Replicas != nil {
+		replicas = *ss.Spec.Replicas
+	}
+	if replicas == 0 {
+		status.SetCondition(v1alpha1.JiraConditionPodReady, v1.ConditionFalse, "ScaledDown",
+			"the statefulset is scaled down to 0 pods")
+		return false, observed
+	}
+	if ss.Status.ReadyReplicas < replicas {
+		status.SetCondition(v1alpha1.JiraConditionPodReady, v1.ConditionFalse, "NotReady",
+			fmt.Sprintf("%d of %d pods are ready", ss.Status.ReadyReplicas, replicas))
+		return false, observed
+	}
+	status.SetCondition(v1alpha1.JiraConditionPodReady, v1.ConditionTrue, "Ready",
+		fmt.Sprintf("%d of %d pods are ready", ss.Status.ReadyReplicas, replicas))
+	return true, observed
+}
+
+// observeService sets the ServiceReady condition.
+func observeService(j *v1alpha1.Jira, status *v1alpha1.JiraStatus) bool {
+	svc := &v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      j.Name,
+			Namespace: j.Namespace,
+		},
+	}
+	if err := getObject(svc); err != nil {
+		setConditionFromError(status, v1alpha1.JiraConditionServiceReady, err)
+		return false
+	}
+	status.SetCondition(v1alpha1.JiraConditionServiceReady, v1.ConditionTrue, "Found", "")
+	return true
+}
+
+// observeReconcile sets the ReconcileFailed condition from the result of the
+// last reconcile. Transient errors, such as conflicts or failed API requests,
+// are only reported through the condition and keep the observed phase.
+func observeReconcile(status *v1alpha1.JiraStatus, err error) {
+	switch {
+	case err == nil:
+		status.SetCondition(v1alpha1.JiraConditionReconcileFailed, v1.ConditionFalse, "Reconciled", "")
+	case isInvalidSpec(err):
+		status.SetCondition(v1alpha1.JiraConditionReconcileFailed, v1.ConditionTrue, "InvalidSpec", err.Error())
+	default:
+		status.SetCondition(v1alpha1.JiraConditionReconcileFailed, v1.ConditionTrue, "Error", err.Error())
+	}
+}
+
+// specError is a reconcile error caused by the spec of the resource. It is
+// not resolved by retrying, so it moves the resource to the Failed phase.
+type specError struct {
+	error
+}
+
+// invalidSpec marks the error as caused by an invalid spec.
+func invalidSpec(err error) error {
+	return specError{err}
+}
+
+// isInvalidSpec returns true if the error is caused by an invalid spec.
+func isInvalidSpec(err error) bool {
+	_, ok := err.(specError)
+	return ok
+}
+
+// setConditionFromError marks the condition as False when the resource is
+// missing and Unknown for any other error.
+func setConditionFromError(status *v1alpha1.JiraStatus, t v1alpha1.JiraConditionType, err error) {
+	if errors.IsNotFound(err) {
+		status.SetCondition(t, v1.ConditionFalse, "NotFound", err.Error())
+		return
+	}
+	status.SetCondition(t, v1.ConditionUnknown, "Error", err.Error())
+}
+
+// serviceEndpoint returns the in-cluster URL of the JIRA service.
+func serviceEndpoint(j *v1alpha1.Jira) string {
+	return fmt.Sprintf("http://%s.%s.svc:%d%s", j.Name, j.Namespace, 8080, contextPath(j))
+}
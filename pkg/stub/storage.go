@@ -0,0 +1,30 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	"k8s.io/api/core/v1"
+)
+
+// emptyDirSource returns the emptyDir of the volume spec, or a default
+// emptyDir if none is defined.
+func emptyDirSource(vol *v1alpha1.JiraVolumeSpec) v1.VolumeSource {
+	if vol != nil && vol.EmptyDir != nil {
+		return v1.VolumeSource{EmptyDir: vol.EmptyDir.DeepCopy()}
+	}
+	return v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+}
@@ -0,0 +1,350 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// statusClient is used to query the JIRA status endpoint.
+var statusClient = &http.Client{Timeout: 5 * time.Second}
+
+// reconcileUpgrade advances the upgrade of a JIRA instance from the deployed
+// version to the version in the spec. An upgrade scales JIRA down, optionally
+// backs up JIRA Home, rolls out the new image and waits for JIRA to finish its
+// upgrade tasks. The spec of the given (defaulted) resource is changed to the
+// image version that should be deployed for the current upgrade phase.
+func reconcileUpgrade(j *v1alpha1.Jira) error {
+	desired := j.Spec.BaseImageVersion
+	if len(j.Status.Version) == 0 {
+		j.Status.Version = deployedVersion(j)
+	}
+	current := j.Status.Version
+
+	u := j.Status.Upgrade
+	if u == nil || (u.IsFinished() && u.ToVersion != desired) {
+		if current == desired {
+			return nil
+		}
+		now := metav1.Now()
+		u = &v1alpha1.JiraUpgradeStatus{
+			FromVersion:    current,
+			ToVersion:      desired,
+			Phase:          v1alpha1.JiraUpgradePhaseScalingDown,
+			StartTime:      &now,
+			PhaseStartTime: &now,
+		}
+		j.Status.Upgrade = u
+		if err := v1alpha1.ValidateUpgrade(current, desired); err != nil {
+			failUpgrade(j, u, "InvalidVersion", err.Error())
+		} else {
+			log.Infof("upgrading jira %s/%s from %s to %s", j.Namespace, j.Name, current, desired)
+			recordEvent(j, v1.EventTypeNormal, "UpgradeStarted", fmt.Sprintf("Upgrading from %s to %s", current, desired))
+		}
+	} else if !u.IsFinished() && u.ToVersion != desired {
+		// The target version was changed during an upgrade, finish the
+		// current upgrade first.
+		log.Infof("upgrade to %s in progress, deferring upgrade to %s", u.ToVersion, desired)
+	}
+
+	if u.PhaseStartTime == nil {
+		now := metav1.Now()
+		u.PhaseStartTime = &now
+	}
+	// JIRA is given the startup time of the probes to start with the new
+	// image and to finish the upgrade tasks.
+	timedOut := time.Since(u.PhaseStartTime.Time) > time.Duration(j.Spec.Probes.StartupSeconds)*time.Second
+
+	phase := u.Phase
+	switch u.Phase {
+	case v1alpha1.JiraUpgradePhaseScalingDown:
+		ss, err := getJiraStatefulSet(j)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && (ss.Status.ObservedGeneration < ss.Generation || ss.Status.Replicas > 0) {
+			break
+		}
+		if j.IsUpgradeBackupEnabled() {
+			u.Phase = v1alpha1.JiraUpgradePhaseBackingUp
+		} else {
+			u.Phase = v1alpha1.JiraUpgradePhaseRolling
+		}
+	case v1alpha1.JiraUpgradePhaseBackingUp:
+		job := newUpgradeBackupJob(j, u)
+		if err := createResource(j, job); err != nil {
+			return err
+		}
+		if err := getLiveObject(job); err != nil {
+			return err
+		}
+		if job.Status.Succeeded > 0 {
+			u.Phase = v1alpha1.JiraUpgradePhaseRolling
+		} else if jobFailed(job) {
+			failUpgrade(j, u, "BackupFailed", fmt.Sprintf("backup job %s failed", job.Name))
+		}
+	case v1alpha1.JiraUpgradePhaseRolling:
+		ss, err := getJiraStatefulSet(j)
+		if err != nil {
+			return err
+		}
+		if ss.Status.ObservedGeneration >= ss.Generation && ss.Status.ReadyReplicas > 0 &&
+			ss.Status.CurrentRevision == ss.Status.UpdateRevision {
+			u.Phase = v1alpha1.JiraUpgradePhaseWaiting
+		} else if timedOut {
+			failUpgrade(j, u, "UpgradeTimedOut", fmt.Sprintf("jira %s did not become ready", u.ToVersion))
+		}
+	case v1alpha1.JiraUpgradePhaseWaiting:
+		state, err := jiraState(j)
+		if err != nil {
+			log.Debugf("jira status not available: %v", err)
+			if timedOut {
+				failUpgrade(j, u, "UpgradeTimedOut", fmt.Sprintf("jira status not available: %v", err))
+			}
+			break
+		}
+		switch state {
+		case "RUNNING":
+			now := metav1.Now()
+			u.Phase = v1alpha1.JiraUpgradePhaseCompleted
+			u.Message = fmt.Sprintf("upgraded from %s to %s", u.FromVersion, u.ToVersion)
+			u.CompletionTime = &now
+			j.Status.Version = u.ToVersion
+			log.Infof("jira %s/%s upgraded to %s", j.Namespace, j.Name, u.ToVersion)
+			recordEvent(j, v1.EventTypeNormal, "UpgradeCompleted", fmt.Sprintf("Upgraded from %s to %s", u.FromVersion, u.ToVersion))
+		case "ERROR":
+			failUpgrade(j, u, "UpgradeTasksFailed", "jira reported an error while running the upgrade tasks")
+		default:
+			if timedOut {
+				failUpgrade(j, u, "UpgradeTimedOut",
+					fmt.Sprintf("jira did not finish the upgrade tasks, the status is %s", state))
+			}
+		}
+	}
+
+	if u.Phase != phase {
+		now := metav1.Now()
+		u.PhaseStartTime = &now
+	}
+	switch u.Phase {
+	case phase, v1alpha1.JiraUpgradePhaseCompleted, v1alpha1.JiraUpgradePhaseFailed:
+	default:
+		recordEvent(j, v1.EventTypeNormal, "UpgradeProgressing",
+			fmt.Sprintf("Upgrade from %s to %s: %s", u.FromVersion, u.ToVersion, upgradeStep(u.Phase)))
+	}
+
+	if upgradeRolledOut(u) {
+		j.Spec.BaseImageVersion = u.ToVersion
+	} else {
+		j.Spec.BaseImageVersion = u.FromVersion
+	}
+	if u.IsFinished() {
+		// The archive is kept on the data volume, the Job is not needed
+		// anymore.
+		return deleteResource(j, newUpgradeBackupJob(j, u))
+	}
+	return nil
+}
+
+// upgradeStep describes what happens in an upgrade phase.
+func upgradeStep(phase v1alpha1.JiraUpgradePhase) string {
+	switch phase {
+	case v1alpha1.JiraUpgradePhaseBackingUp:
+		return "JIRA stopped, backing up JIRA Home"
+	case v1alpha1.JiraUpgradePhaseRolling:
+		return "rolling out the new image"
+	case v1alpha1.JiraUpgradePhaseWaiting:
+		return "waiting for JIRA to finish the upgrade tasks"
+	}
+	return string(phase)
+}
+
+// upgradeRolledOut returns true if the new image is deployed in the current
+// upgrade phase. A failed upgrade only goes back to the previous image when
+// it failed before the new image was rolled out. Once JIRA has started with
+// the new version it may have upgraded JIRA Home and the database, so it keeps
+// the new image until the backup is restored.
+func upgradeRolledOut(u *v1alpha1.JiraUpgradeStatus) bool {
+	switch u.Phase {
+	case v1alpha1.JiraUpgradePhaseScalingDown, v1alpha1.JiraUpgradePhaseBackingUp:
+		return false
+	case v1alpha1.JiraUpgradePhaseFailed:
+		return u.Reason != "InvalidVersion" && u.Reason != "BackupFailed"
+	}
+	return true
+}
+
+// failUpgrade marks the upgrade as failed.
+func failUpgrade(j *v1alpha1.Jira, u *v1alpha1.JiraUpgradeStatus, reason, message string) {
+	log.Errorf("upgrade from %s to %s failed: %s", u.FromVersion, u.ToVersion, message)
+	recordEvent(j, v1.EventTypeWarning, "UpgradeFailed", message)
+	now := metav1.Now()
+	u.Phase = v1alpha1.JiraUpgradePhaseFailed
+	u.Reason = reason
+	u.Message = message
+	u.CompletionTime = &now
+}
+
+// isScaledDown returns true if JIRA must be stopped for the current upgrade
+// phase, a restore or a snapshot backup.
+func isScaledDown(j *v1alpha1.Jira) bool {
+	if isRestoring(j) || isQuiesced(j) {
+		return true
+	}
+	u := j.Status.Upgrade
+	return u != nil && (u.Phase == v1alpha1.JiraUpgradePhaseScalingDown || u.Phase == v1alpha1.JiraUpgradePhaseBackingUp)
+}
+
+// deployedVersion returns the version of the image in the existing JIRA
+// StatefulSet, or the version in the spec for a new deployment.
+func deployedVersion(j *v1alpha1.Jira) string {
+	ss, err := getJiraStatefulSet(j)
+	if err != nil {
+		return j.Spec.BaseImageVersion
+	}
+	for _, c := range ss.Spec.Template.Spec.Containers {
+		if c.Name != "jira" {
+			continue
+		}
+		if i := strings.LastIndex(c.Image, ":"); i >= 0 && !strings.Contains(c.Image[i:], "/") {
+			return c.Image[i+1:]
+		}
+	}
+	return j.Spec.BaseImageVersion
+}
+
+// getJiraStatefulSet returns the live JIRA StatefulSet.
+func getJiraStatefulSet(j *v1alpha1.Jira) (*appsv1.StatefulSet, error) {
+	ss := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      j.Name,
+			Namespace: j.Namespace,
+		},
+	}
+	err := getLiveObject(ss)
+	return ss, err
+}
+
+// jiraState returns the state reported by the JIRA status endpoint.
+func jiraState(j *v1alpha1.Jira) (string, error) {
+	return jiraStateAt(serviceEndpoint(j))
+}
+
+// jiraStateAt returns the state reported by the status endpoint of the JIRA
+// instance at the given base URL.
+func jiraStateAt(base string) (string, error) {
+	resp, err := statusClient.Get(base + "/status")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var status struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", err
+	}
+	return status.State, nil
+}
+
+// jobFailed returns true if the Job has a Failed condition.
+func jobFailed(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeBackupLabels returns the labels of the upgrade backup Jobs and their
+// pods. They differ from the labels of JIRA so that the pods are not selected
+// by the JIRA Services and do not hold up the finalizer.
+func upgradeBackupLabels(j *v1alpha1.Jira) map[string]string {
+	return map[string]string{
+		"app":     "jira-upgrade-backup",
+		"cluster": j.Name,
+	}
+}
+
+// newUpgradeBackupJob returns a Job that archives JIRA Home to the backups
+// directory on the data volume.
+func newUpgradeBackupJob(j *v1alpha1.Jira, u *v1alpha1.JiraUpgradeStatus) *batchv1.Job {
+	mp := j.Spec.DataMountPath
+	archive := fmt.Sprintf("%s/backups/jira-home-%s-to-%s.tar.gz", mp, u.FromVersion, u.ToVersion)
+	backoffLimit := int32(2)
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-upgrade-backup-%s", j.Name, strings.Replace(u.ToVersion, ".", "-", -1)),
+			Namespace:       j.Namespace,
+			OwnerReferences: ownerRef(j),
+			Labels:          upgradeBackupLabels(j),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: upgradeBackupLabels(j),
+				},
+				Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyOnFailure,
+					Containers: []v1.Container{{
+						Name:  "backup",
+						Image: "busybox",
+						Command: []string{
+							"/bin/sh",
+							"-c",
+							fmt.Sprintf("mkdir -p %s/backups && tar czf %s --exclude=./backups -C %s .", mp, archive, mp),
+						},
+						VolumeMounts: []v1.VolumeMount{{
+							Name:      "jira-data",
+							MountPath: mp,
+						}},
+					}},
+					Volumes: []v1.Volume{{
+						Name: "jira-data",
+						VolumeSource: v1.VolumeSource{
+							PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+								ClaimName: dataClaimName(j, 0),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1beta1"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// conversionReview is the ConversionReview of the apiextensions.k8s.io/v1beta1
+// API sent by the API server to convert custom resources.
+type conversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *conversionRequest  `json:"request,omitempty"`
+	Response        *conversionResponse `json:"response,omitempty"`
+}
+
+// conversionRequest is the request of a ConversionReview.
+type conversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+// conversionResponse is the response of a ConversionReview.
+type conversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// serveConversion converts the objects of a ConversionReview to the desired
+// API version.
+func serveConversion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	review := &conversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil || review.Request == nil {
+		http.Error(w, "failed to decode conversion review", http.StatusBadRequest)
+		return
+	}
+
+	resp := &conversionResponse{
+		UID:    review.Request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+	for _, obj := range review.Request.Objects {
+		converted, err := convert(obj.Raw, review.Request.DesiredAPIVersion)
+		if err != nil {
+			log.Errorf("Failed to convert object: %v", err)
+			resp.ConvertedObjects = nil
+			resp.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			break
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	review.Request = nil
+	review.Response = resp
+
+	out, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(out); err != nil {
+		log.Errorf("failed to write conversion response: %v", err)
+	}
+}
+
+// convert converts a serialized Jira or JiraRestore to the API version.
+func convert(raw []byte, apiVersion string) ([]byte, error) {
+	tm := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &tm); err != nil {
+		return nil, err
+	}
+	var out interface{}
+	var err error
+	switch tm.Kind {
+	case "Jira":
+		var j *v1alpha1.Jira
+		if j, err = decodeJira(raw); err != nil {
+			return nil, err
+		}
+		switch apiVersion {
+		case v1alpha1.SchemeGroupVersion.String():
+			out = j
+		case v1beta1.SchemeGroupVersion.String():
+			out, err = v1beta1.ConvertJiraFromV1alpha1(j)
+		default:
+			return nil, fmt.Errorf("unsupported API version %s", apiVersion)
+		}
+	case "JiraRestore":
+		var r *v1alpha1.JiraRestore
+		if r, err = decodeJiraRestore(raw); err != nil {
+			return nil, err
+		}
+		switch apiVersion {
+		case v1alpha1.SchemeGroupVersion.String():
+			out = r
+		case v1beta1.SchemeGroupVersion.String():
+			out, err = v1beta1.ConvertJiraRestoreFromV1alpha1(r)
+		default:
+			return nil, fmt.Errorf("unsupported API version %s", apiVersion)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported kind %s", tm.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// decodeJira decodes a Jira of any served API version into the v1alpha1 API
+// the operator works with.
+func decodeJira(raw []byte) (*v1alpha1.Jira, error) {
+	tm := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &tm); err != nil {
+		return nil, err
+	}
+	if tm.APIVersion == v1beta1.SchemeGroupVersion.String() {
+		j := &v1beta1.Jira{}
+		if err := json.Unmarshal(raw, j); err != nil {
+			return nil, err
+		}
+		return v1beta1.ConvertJiraToV1alpha1(j)
+	}
+	j := &v1alpha1.Jira{}
+	if err := json.Unmarshal(raw, j); err != nil {
+		return nil, err
+	}
+	j.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	return j, nil
+}
+
+// decodeJiraRestore decodes a JiraRestore of any served API version into the
+// v1alpha1 API.
+func decodeJiraRestore(raw []byte) (*v1alpha1.JiraRestore, error) {
+	tm := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &tm); err != nil {
+		return nil, err
+	}
+	if tm.APIVersion == v1beta1.SchemeGroupVersion.String() {
+		r := &v1beta1.JiraRestore{}
+		if err := json.Unmarshal(raw, r); err != nil {
+			return nil, err
+		}
+		return v1beta1.ConvertJiraRestoreToV1alpha1(r)
+	}
+	r := &v1alpha1.JiraRestore{}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, err
+	}
+	r.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	return r, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1beta1"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// patchOperation is a JSON patch operation.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// mutate applies the defaults to created and updated Jira and JiraRestore
+// resources, so the effective spec is stored.
+func mutate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	var orig, defaulted interface{}
+	beta := req.Kind.Version == v1beta1.SchemeGroupVersion.Version
+	switch req.Kind.Kind {
+	case "Jira":
+		j, err := decodeJira(req.Object.Raw)
+		if err != nil {
+			return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("failed to decode Jira: %v", err))
+		}
+		// Some defaults are derived from the name, which is not yet known if
+		// the name is generated. The operator persists them later.
+		if len(j.Name) == 0 || j.DeletionTimestamp != nil {
+			return allow()
+		}
+		if len(j.Namespace) == 0 {
+			j.Namespace = req.Namespace
+		}
+		jira := j.DeepCopy()
+		jira.SetDefaults()
+		orig, defaulted = j.Spec, jira.Spec
+		if beta {
+			b := &v1beta1.Jira{}
+			if err := json.Unmarshal(req.Object.Raw, b); err != nil {
+				return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("failed to decode Jira: %v", err))
+			}
+			converted, err := v1beta1.ConvertJiraFromV1alpha1(jira)
+			if err != nil {
+				return deny(metav1.StatusReasonInternalError, http.StatusInternalServerError, fmt.Sprintf("failed to convert Jira: %v", err))
+			}
+			orig, defaulted = b.Spec, converted.Spec
+		}
+	case "JiraRestore":
+		r, err := decodeJiraRestore(req.Object.Raw)
+		if err != nil {
+			return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("failed to decode JiraRestore: %v", err))
+		}
+		restore := r.DeepCopy()
+		restore.SetDefaults()
+		// The restore spec is the same in all API versions.
+		orig, defaulted = r.Spec, restore.Spec
+	default:
+		return allow()
+	}
+	if equality.Semantic.DeepEqual(orig, defaulted) {
+		return allow()
+	}
+
+	// The add operation replaces the spec if it exists.
+	patch, err := json.Marshal([]patchOperation{{Op: "add", Path: "/spec", Value: defaulted}})
+	if err != nil {
+		return deny(metav1.StatusReasonInternalError, http.StatusInternalServerError, fmt.Sprintf("failed to encode patch: %v", err))
+	}
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	resp := allow()
+	resp.Patch = patch
+	resp.PatchType = &patchType
+	return resp
+}
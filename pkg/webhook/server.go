@@ -0,0 +1,133 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook implements the admission webhooks of the operator.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CertDirEnvVar is the environment variable with the directory of the
+	// TLS certificate (tls.crt) and key (tls.key) of the webhook server. The
+	// webhook server is only started if the certificate exists.
+	CertDirEnvVar = "WEBHOOK_CERT_DIR"
+	// PortEnvVar is the environment variable with the port of the webhook
+	// server.
+	PortEnvVar = "WEBHOOK_PORT"
+	// DefaultPort is the default port of the webhook server.
+	DefaultPort = "8443"
+
+	// ValidatePath is the path of the validating webhook.
+	ValidatePath = "/validate"
+	// MutatePath is the path of the defaulting webhook.
+	MutatePath = "/mutate"
+	// ConvertPath is the path of the conversion webhook.
+	ConvertPath = "/convert"
+)
+
+// admitFunc admits or rejects an admission request.
+type admitFunc func(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse
+
+// Enabled returns true if a certificate for the webhook server exists.
+func Enabled() bool {
+	certDir := os.Getenv(CertDirEnvVar)
+	if len(certDir) == 0 {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(certDir, "tls.crt"))
+	return err == nil
+}
+
+// Serve starts the HTTPS webhook server and blocks until it fails.
+func Serve() error {
+	certDir := os.Getenv(CertDirEnvVar)
+	port := os.Getenv(PortEnvVar)
+	if len(port) == 0 {
+		port = DefaultPort
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, serve(validate))
+	mux.HandleFunc(MutatePath, serve(mutate))
+	mux.HandleFunc(ConvertPath, serveConversion)
+
+	log.Infof("Serving admission webhooks on :%s", port)
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	return server.ListenAndServeTLS(filepath.Join(certDir, "tls.crt"), filepath.Join(certDir, "tls.key"))
+}
+
+// serve returns a handler that decodes an AdmissionReview, passes the
+// request to the admit function and writes the response.
+func serve(admit admitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+			return
+		}
+		review := &admissionv1beta1.AdmissionReview{}
+		if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+			http.Error(w, "failed to decode admission review", http.StatusBadRequest)
+			return
+		}
+
+		resp := admit(review.Request)
+		resp.UID = review.Request.UID
+		review.Response = resp
+		review.Request = nil
+
+		out, err := json.Marshal(review)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(out); err != nil {
+			log.Errorf("failed to write admission response: %v", err)
+		}
+	}
+}
+
+// allow returns a response that admits the request.
+func allow() *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+// deny returns a response that rejects the request with the message.
+func deny(reason metav1.StatusReason, code int32, message string) *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  reason,
+			Code:    code,
+			Message: message,
+		},
+	}
+}
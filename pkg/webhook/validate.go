@@ -0,0 +1,387 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1beta1"
+	"github.com/jmckind/jira-operator/pkg/schedule"
+
+	"github.com/ghodss/yaml"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	// imageNameRegexp matches an image name without tag or digest, e.g.
+	// cptactionhank/atlassian-jira or registry:5000/atlassian/jira.
+	imageNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9.-]+(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)*$`)
+	// imageTagRegexp matches a valid image tag.
+	imageTagRegexp = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	// durationRegexp matches a Prometheus duration, e.g. 30s or 1m.
+	durationRegexp = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d|w|y)$`)
+)
+
+// betaFieldPaths maps the field paths of v1alpha1 to v1beta1, so rejections
+// of v1beta1 resources name the fields of their API version.
+var betaFieldPaths = strings.NewReplacer(
+	"spec.base_image_version", "spec.image.tag",
+	"spec.base_image", "spec.image.repository",
+	"spec.data_mount_path", "spec.storage.localHome.mountPath",
+	"spec.pod.resources", "spec.resources",
+	"spec.pod.persistentVolumeClaimSpec", "spec.storage.localHome.persistentVolumeClaimSpec",
+	"spec.database.poolMinSize", "spec.database.pool.minSize",
+	"spec.database.poolMaxSize", "spec.database.pool.maxSize",
+)
+
+// validate admits or rejects the creation and update of Jira and JiraRestore
+// resources.
+func validate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	var errs field.ErrorList
+	switch req.Kind.Kind {
+	case "Jira":
+		j, err := decodeJira(req.Object.Raw)
+		if err != nil {
+			return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("failed to decode Jira: %v", err))
+		}
+		errs = validateJira(j)
+		if req.Operation == admissionv1beta1.Update {
+			old, err := decodeJira(req.OldObject.Raw)
+			if err != nil {
+				return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("failed to decode Jira: %v", err))
+			}
+			errs = append(errs, validateJiraUpdate(j, old)...)
+		}
+	case "JiraRestore":
+		r, err := decodeJiraRestore(req.Object.Raw)
+		if err != nil {
+			return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("failed to decode JiraRestore: %v", err))
+		}
+		errs = validateJiraRestore(r)
+		if req.Operation == admissionv1beta1.Update {
+			old, err := decodeJiraRestore(req.OldObject.Raw)
+			if err != nil {
+				return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("failed to decode JiraRestore: %v", err))
+			}
+			restore, prev := r.DeepCopy(), old.DeepCopy()
+			restore.SetDefaults()
+			prev.SetDefaults()
+			if !equality.Semantic.DeepEqual(restore.Spec, prev.Spec) {
+				errs = append(errs, field.Forbidden(field.NewPath("spec"), "field is immutable"))
+			}
+		}
+	default:
+		return allow()
+	}
+	if len(errs) == 0 {
+		return allow()
+	}
+	message := fmt.Sprintf("%s %q is invalid: %v", req.Kind.Kind, req.Name, errs.ToAggregate())
+	if req.Kind.Version == v1beta1.SchemeGroupVersion.Version {
+		message = betaFieldPaths.Replace(message)
+	}
+	return deny(metav1.StatusReasonInvalid, http.StatusUnprocessableEntity, message)
+}
+
+// validateJira validates the spec of a Jira resource. The defaults are
+// applied to a copy first, so omitted fields are valid.
+func validateJira(j *v1alpha1.Jira) field.ErrorList {
+	jira := j.DeepCopy()
+	jira.SetDefaults()
+	spec := jira.Spec
+	path := field.NewPath("spec")
+
+	var errs field.ErrorList
+	if !imageNameRegexp.MatchString(spec.BaseImage) {
+		errs = append(errs, field.Invalid(path.Child("base_image"), spec.BaseImage, "must be an image name without tag or digest"))
+	}
+	if !imageTagRegexp.MatchString(spec.BaseImageVersion) {
+		errs = append(errs, field.Invalid(path.Child("base_image_version"), spec.BaseImageVersion,
+			"must be a valid image tag of at most 128 letters, digits, underscores, periods and dashes"))
+	}
+	if !strings.HasPrefix(spec.DataMountPath, "/") {
+		errs = append(errs, field.Invalid(path.Child("data_mount_path"), spec.DataMountPath, "must be an absolute path"))
+	}
+	if p := spec.Pod; p != nil {
+		errs = append(errs, v1alpha1.ValidateResources(p.Resources, path.Child("pod", "resources"))...)
+		if p.PersistentVolumeClaimSpec != nil {
+			errs = append(errs, v1alpha1.ValidateClaimSpec(p.PersistentVolumeClaimSpec, path.Child("pod", "persistentVolumeClaimSpec"))...)
+		}
+	}
+	errs = append(errs, jira.ValidateStorage()...)
+	errs = append(errs, jira.ValidateCluster()...)
+	if db := spec.Database; db != nil {
+		errs = append(errs, validateDatabase(db, path.Child("database"))...)
+	}
+	if e := spec.Expose; e != nil {
+		errs = append(errs, validateExpose(e, path.Child("expose"))...)
+	}
+	if b := spec.Backup; b != nil {
+		errs = append(errs, validateBackup(jira, b, path.Child("backup"))...)
+	}
+	if m := spec.Monitoring; m != nil {
+		errs = append(errs, validateMonitoring(jira, m, path.Child("monitoring"))...)
+	}
+	return errs
+}
+
+// validateJiraUpdate rejects changes to fields the operator cannot apply to
+// existing resources.
+func validateJiraUpdate(j, old *v1alpha1.Jira) field.ErrorList {
+	path := field.NewPath("spec")
+
+	// The pod resources are rolled out by the StatefulSet. The deprecated PVC
+	// spec of the pod policy may move to the local home, which is checked with
+	// the effective claim spec below.
+	var errs field.ErrorList
+	jira, prev := j.DeepCopy(), old.DeepCopy()
+	jira.SetDefaults()
+	prev.SetDefaults()
+	if jira.Spec.DataMountPath != prev.Spec.DataMountPath {
+		errs = append(errs, field.Forbidden(path.Child("data_mount_path"),
+			fmt.Sprintf("JIRA Home cannot be moved from %s", prev.Spec.DataMountPath)))
+	}
+	if !equality.Semantic.DeepEqual(jira.LocalHomeClaimSpec(), prev.LocalHomeClaimSpec()) {
+		errs = append(errs, field.Forbidden(path.Child("storage", "localHome", "persistentVolumeClaimSpec"),
+			"the volume claim of the local home is immutable"))
+	}
+	if jira.IsClustered() != prev.IsClustered() {
+		errs = append(errs, field.Forbidden(path.Child("storage", "sharedHome"), "the shared home cannot be added or removed"))
+	} else if jira.IsClustered() {
+		errs = append(errs, validateClaimUpdate(jira.Spec.Storage.SharedHome.PersistentVolumeClaimSpec,
+			prev.Spec.Storage.SharedHome.PersistentVolumeClaimSpec, path.Child("storage", "sharedHome", "persistentVolumeClaimSpec"))...)
+	}
+	if v := jira.Spec.BaseImageVersion; v != prev.Spec.BaseImageVersion && v != prev.Status.Version {
+		// Going back to the deployed version cancels or reverts an upgrade.
+		// Other changes from a version that is not a JIRA version are left to
+		// the upgrade of the operator.
+		if _, err := v1alpha1.ParseVersion(prev.Spec.BaseImageVersion); err == nil {
+			if err := v1alpha1.ValidateUpgrade(prev.Spec.BaseImageVersion, v); err != nil {
+				errs = append(errs, field.Forbidden(path.Child("base_image_version"), err.Error()))
+			}
+		}
+	}
+	if databaseType(jira) != databaseType(prev) {
+		errs = append(errs, field.Forbidden(path.Child("database", "type"),
+			fmt.Sprintf("the database cannot be changed from %s", databaseType(prev))))
+	}
+	return errs
+}
+
+// validateJiraRestore validates the spec of a JiraRestore resource.
+func validateJiraRestore(r *v1alpha1.JiraRestore) field.ErrorList {
+	path := field.NewPath("spec")
+
+	var errs field.ErrorList
+	if len(r.Spec.JiraName) == 0 {
+		errs = append(errs, field.Required(path.Child("jiraName"), "the name of the Jira resource to restore is required"))
+	}
+	if len(r.Spec.Backup) == 0 {
+		errs = append(errs, field.Required(path.Child("backup"), "the name of the backup to restore is required"))
+	}
+	if s3 := r.Spec.S3; s3 != nil {
+		errs = append(errs, validateS3(s3, path.Child("s3"))...)
+	}
+	return errs
+}
+
+// validateClaimUpdate allows the storage request of an existing claim to grow
+// and rejects all other changes.
+func validateClaimUpdate(spec, old *v1.PersistentVolumeClaimSpec, path *field.Path) field.ErrorList {
+	if spec == nil || old == nil {
+		if spec != old {
+			return field.ErrorList{field.Forbidden(path, "the volume cannot be changed between a claim and an emptyDir")}
+		}
+		return nil
+	}
+	size, oldSize := spec.Resources.Requests[v1.ResourceStorage], old.Resources.Requests[v1.ResourceStorage]
+	if size.Cmp(oldSize) < 0 {
+		return field.ErrorList{field.Forbidden(path.Child("resources", "requests").Key(string(v1.ResourceStorage)),
+			fmt.Sprintf("the volume cannot be shrunk from %s", oldSize.String()))}
+	}
+	a, b := spec.DeepCopy(), old.DeepCopy()
+	delete(a.Resources.Requests, v1.ResourceStorage)
+	delete(b.Resources.Requests, v1.ResourceStorage)
+	if !equality.Semantic.DeepEqual(a, b) {
+		return field.ErrorList{field.Forbidden(path, "only the storage request of the volume can be changed")}
+	}
+	return nil
+}
+
+// validateDatabase validates the database type, server and pool sizes.
+func validateDatabase(db *v1alpha1.JiraDatabaseSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch db.Type {
+	case v1alpha1.DatabaseTypeH2:
+		return nil
+	case v1alpha1.DatabaseTypePostgres, v1alpha1.DatabaseTypeMySQL, v1alpha1.DatabaseTypeMSSQL, v1alpha1.DatabaseTypeOracle:
+	default:
+		return field.ErrorList{field.NotSupported(path.Child("type"), db.Type, []string{
+			string(v1alpha1.DatabaseTypeH2), string(v1alpha1.DatabaseTypePostgres), string(v1alpha1.DatabaseTypeMySQL),
+			string(v1alpha1.DatabaseTypeMSSQL), string(v1alpha1.DatabaseTypeOracle),
+		})}
+	}
+	if len(db.Host) == 0 {
+		errs = append(errs, field.Required(path.Child("host"), fmt.Sprintf("the host of the %s database is required", db.Type)))
+	}
+	if len(db.Name) == 0 {
+		errs = append(errs, field.Required(path.Child("name"), fmt.Sprintf("the name of the %s database is required", db.Type)))
+	}
+	errs = append(errs, v1alpha1.ValidatePort(db.Port, path.Child("port"))...)
+	if db.PoolMinSize < 0 {
+		errs = append(errs, field.Invalid(path.Child("poolMinSize"), db.PoolMinSize, "must be greater than or equal to 0"))
+	}
+	if db.PoolMaxSize < db.PoolMinSize {
+		errs = append(errs, field.Invalid(path.Child("poolMaxSize"), db.PoolMaxSize,
+			fmt.Sprintf("must be greater than or equal to poolMinSize %d", db.PoolMinSize)))
+	}
+	return errs
+}
+
+// validateExpose validates the Service type, Ingress, Route and proxy.
+func validateExpose(e *v1alpha1.JiraExposeSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch e.ServiceType {
+	case v1.ServiceTypeClusterIP, v1.ServiceTypeNodePort, v1.ServiceTypeLoadBalancer:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("serviceType"), e.ServiceType, []string{
+			string(v1.ServiceTypeClusterIP), string(v1.ServiceTypeNodePort), string(v1.ServiceTypeLoadBalancer),
+		}))
+	}
+	if e.Ingress != nil && len(e.Ingress.Host) == 0 {
+		errs = append(errs, field.Required(path.Child("ingress", "host"), "the host name of the Ingress is required"))
+	}
+	if e.Route != nil {
+		switch e.Route.TLSTermination {
+		case "", "edge", "passthrough", "reencrypt":
+		default:
+			errs = append(errs, field.NotSupported(path.Child("route", "tlsTermination"), e.Route.TLSTermination,
+				[]string{"edge", "passthrough", "reencrypt"}))
+		}
+	}
+	if p := e.Proxy; p != nil {
+		if p.Port != 0 {
+			errs = append(errs, v1alpha1.ValidatePort(p.Port, path.Child("proxy", "port"))...)
+		}
+		switch p.Scheme {
+		case "", "http", "https":
+		default:
+			errs = append(errs, field.NotSupported(path.Child("proxy", "scheme"), p.Scheme, []string{"http", "https"}))
+		}
+	}
+	return errs
+}
+
+// validateBackup validates the backup mode, schedule and object storage.
+func validateBackup(j *v1alpha1.Jira, b *v1alpha1.JiraBackupSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if _, err := schedule.Parse(b.Schedule); err != nil {
+		errs = append(errs, field.Invalid(path.Child("schedule"), b.Schedule, err.Error()))
+	}
+	if b.Retention < 0 {
+		errs = append(errs, field.Invalid(path.Child("retention"), b.Retention, "must be greater than or equal to 0"))
+	}
+	switch b.Mode {
+	case v1alpha1.JiraBackupModeArchive:
+		errs = append(errs, validateS3(&b.S3, path.Child("s3"))...)
+	case v1alpha1.JiraBackupModeSnapshot:
+		if !j.IsPVEnabled() {
+			errs = append(errs, field.Forbidden(path.Child("mode"), "snapshot backups require a persistentVolumeClaimSpec for the local home"))
+		}
+		if j.IsClustered() {
+			errs = append(errs, field.Forbidden(path.Child("mode"), "snapshot backups do not cover the shared home of a cluster"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("mode"), b.Mode, []string{
+			string(v1alpha1.JiraBackupModeArchive), string(v1alpha1.JiraBackupModeSnapshot),
+		}))
+	}
+	return errs
+}
+
+// validateS3 requires the endpoint, bucket and credentials of the object
+// storage.
+func validateS3(s3 *v1alpha1.JiraBackupS3Spec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if !strings.HasPrefix(s3.Endpoint, "http://") && !strings.HasPrefix(s3.Endpoint, "https://") {
+		errs = append(errs, field.Invalid(path.Child("endpoint"), s3.Endpoint, "must be an http or https URL"))
+	}
+	if len(s3.Bucket) == 0 {
+		errs = append(errs, field.Required(path.Child("bucket"), "the name of the bucket is required"))
+	}
+	if len(s3.CredentialsSecret) == 0 {
+		errs = append(errs, field.Required(path.Child("credentialsSecret"), "the name of the Secret with the S3 credentials is required"))
+	}
+	return errs
+}
+
+// validateMonitoring validates the mode, the ports and the configuration of
+// the JMX exporter. The ports must not collide with the ports of JIRA.
+func validateMonitoring(j *v1alpha1.Jira, m *v1alpha1.JiraMonitoringSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	used := map[int32]string{8080: "the http port"}
+	if c := j.Spec.Cluster; c != nil && j.IsClustered() {
+		used[c.EhcacheListenerPort] = "cluster.ehcacheListenerPort"
+		used[c.EhcacheObjectPort] = "cluster.ehcacheObjectPort"
+	}
+	checkPort := func(port int32, path *field.Path) {
+		if perrs := v1alpha1.ValidatePort(port, path); len(perrs) > 0 {
+			errs = append(errs, perrs...)
+		} else if other, ok := used[port]; ok {
+			errs = append(errs, field.Invalid(path, port, fmt.Sprintf("must differ from %s", other)))
+		}
+	}
+	checkPort(m.Port, path.Child("port"))
+
+	switch m.Mode {
+	case v1alpha1.JiraMonitoringModeAgent:
+		if !strings.HasPrefix(m.AgentURL, "http://") && !strings.HasPrefix(m.AgentURL, "https://") {
+			errs = append(errs, field.Invalid(path.Child("agentURL"), m.AgentURL, "must be an http or https URL"))
+		}
+	case v1alpha1.JiraMonitoringModeSidecar:
+		used[m.Port] = "port"
+		checkPort(m.JMXPort, path.Child("jmxPort"))
+	default:
+		errs = append(errs, field.NotSupported(path.Child("mode"), m.Mode, []string{
+			string(v1alpha1.JiraMonitoringModeAgent), string(v1alpha1.JiraMonitoringModeSidecar),
+		}))
+	}
+	if len(m.Config) > 0 {
+		var config map[string]interface{}
+		if err := yaml.Unmarshal([]byte(m.Config), &config); err != nil {
+			errs = append(errs, field.Invalid(path.Child("config"), m.Config, fmt.Sprintf("must be a YAML object: %v", err)))
+		}
+	}
+	if sm := m.ServiceMonitor; sm != nil && len(sm.Interval) > 0 && !durationRegexp.MatchString(sm.Interval) {
+		errs = append(errs, field.Invalid(path.Child("serviceMonitor", "interval"), sm.Interval, "must be a duration, e.g. 30s"))
+	}
+	return errs
+}
+
+// databaseType returns the database type of a defaulted Jira resource.
+func databaseType(j *v1alpha1.Jira) v1alpha1.DatabaseType {
+	if j.Spec.Database == nil {
+		return v1alpha1.DatabaseTypeH2
+	}
+	return j.Spec.Database.Type
+}
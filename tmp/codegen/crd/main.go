@@ -0,0 +1,301 @@
+// Copyright 2018 Jira Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command crd generates the CustomResourceDefinitions of the operator from
+// the API types. The OpenAPI v3 schemas of each API version are derived from
+// the Go types and the descriptions from the doc comments of their fields.
+// v1alpha1 is the storage version, other versions are converted by the
+// conversion webhook of the operator.
+//
+//	go run ./tmp/codegen/crd > deploy/crd.yaml
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1alpha1"
+	"github.com/jmckind/jira-operator/pkg/apis/jira/v1beta1"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// schema is an OpenAPI v3 schema.
+type schema map[string]interface{}
+
+var (
+	timeType     = reflect.TypeOf(metav1.Time{})
+	quantityType = reflect.TypeOf(resource.Quantity{})
+	intOrStrType = reflect.TypeOf(intstr.IntOrString{})
+)
+
+// modulePath is the import path of the repository root.
+const modulePath = "github.com/jmckind/jira-operator/"
+
+// generator derives schemas from Go types.
+type generator struct {
+	// docs maps "package.Type.Field" of the API packages to the doc comment
+	// of the field.
+	docs map[string]string
+}
+
+func main() {
+	g := &generator{docs: map[string]string{}}
+	for _, t := range []reflect.Type{reflect.TypeOf(v1alpha1.Jira{}), reflect.TypeOf(v1beta1.Jira{})} {
+		if err := g.parseDocs(t.PkgPath()); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to parse API docs: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	crds := []schema{
+		{
+			"apiVersion": "apiextensions.k8s.io/v1beta1",
+			"kind":       "CustomResourceDefinition",
+			"metadata":   schema{"name": "jiras.app.redhat.com"},
+			"spec": schema{
+				"group":   v1alpha1.SchemeGroupVersion.Group,
+				"version": v1alpha1.SchemeGroupVersion.Version,
+				"scope":   "Namespaced",
+				"names": schema{
+					"kind":     "Jira",
+					"listKind": "JiraList",
+					"plural":   "jiras",
+					"singular": "jira",
+				},
+				"versions": []schema{
+					g.version(v1alpha1.SchemeGroupVersion.Version, true, reflect.TypeOf(v1alpha1.Jira{})),
+					g.version(v1beta1.SchemeGroupVersion.Version, false, reflect.TypeOf(v1beta1.Jira{})),
+				},
+				"conversion": conversion(),
+				"subresources": schema{
+					"status": schema{},
+					"scale": schema{
+						"specReplicasPath":   ".spec.replicas",
+						"statusReplicasPath": ".status.replicas",
+						"labelSelectorPath":  ".status.selector",
+					},
+				},
+				"additionalPrinterColumns": []schema{
+					{"name": "Phase", "type": "string", "JSONPath": ".status.phase", "description": "The lifecycle phase of JIRA"},
+					{"name": "Version", "type": "string", "JSONPath": ".status.version", "description": "The deployed JIRA version"},
+					{"name": "URL", "type": "string", "JSONPath": ".status.url", "description": "The external URL of JIRA"},
+					{"name": "Age", "type": "date", "JSONPath": ".metadata.creationTimestamp"},
+				},
+			},
+		},
+		{
+			"apiVersion": "apiextensions.k8s.io/v1beta1",
+			"kind":       "CustomResourceDefinition",
+			"metadata":   schema{"name": "jirarestores.app.redhat.com"},
+			"spec": schema{
+				"group":   v1alpha1.SchemeGroupVersion.Group,
+				"version": v1alpha1.SchemeGroupVersion.Version,
+				"scope":   "Namespaced",
+				"names": schema{
+					"kind":     "JiraRestore",
+					"listKind": "JiraRestoreList",
+					"plural":   "jirarestores",
+					"singular": "jirarestore",
+				},
+				"versions": []schema{
+					g.version(v1alpha1.SchemeGroupVersion.Version, true, reflect.TypeOf(v1alpha1.JiraRestore{})),
+					g.version(v1beta1.SchemeGroupVersion.Version, false, reflect.TypeOf(v1beta1.JiraRestore{})),
+				},
+				"conversion": conversion(),
+				"subresources": schema{
+					"status": schema{},
+				},
+				"additionalPrinterColumns": []schema{
+					{"name": "Jira", "type": "string", "JSONPath": ".spec.jiraName", "description": "The restored Jira resource"},
+					{"name": "Backup", "type": "string", "JSONPath": ".spec.backup", "description": "The restored backup"},
+					{"name": "Phase", "type": "string", "JSONPath": ".status.phase", "description": "The phase of the restore"},
+					{"name": "Age", "type": "date", "JSONPath": ".metadata.creationTimestamp"},
+				},
+			},
+		},
+	}
+
+	fmt.Println("# Code generated by tmp/codegen/crd. DO NOT EDIT.")
+	for i, crd := range crds {
+		out, err := yaml.Marshal(crd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal CRD: %v\n", err)
+			os.Exit(1)
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(out))
+	}
+}
+
+// version returns a served version of a CRD with the schema of the type.
+func (g *generator) version(name string, storage bool, t reflect.Type) schema {
+	return schema{
+		"name":    name,
+		"served":  true,
+		"storage": storage,
+		"schema":  schema{"openAPIV3Schema": g.object(t)},
+	}
+}
+
+// conversion returns the conversion settings of a CRD. The namespace of the
+// webhook Service and the CA bundle must be set when deploying the operator.
+func conversion() schema {
+	return schema{
+		"strategy":                 "Webhook",
+		"conversionReviewVersions": []string{"v1beta1"},
+		"webhookClientConfig": schema{
+			"service": schema{
+				"namespace": "default",
+				"name":      "jira-operator-webhook",
+				"path":      "/convert",
+			},
+			"caBundle": "",
+		},
+	}
+}
+
+// object returns the schema of a top-level resource type. Only the spec and
+// status are described, the type and object metadata are validated by the
+// API server.
+func (g *generator) object(t reflect.Type) schema {
+	props := schema{}
+	for _, name := range []string{"Spec", "Status"} {
+		f, ok := t.FieldByName(name)
+		if !ok {
+			continue
+		}
+		s := g.schema(f.Type)
+		if doc, ok := g.docs[t.PkgPath()+"."+t.Name()+"."+f.Name]; ok {
+			s["description"] = doc
+		}
+		props[jsonName(f)] = s
+	}
+	return schema{"type": "object", "properties": props}
+}
+
+// schema returns the schema of a Go type.
+func (g *generator) schema(t reflect.Type) schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case quantityType, intOrStrType:
+		return schema{
+			"anyOf":                      []schema{{"type": "integer"}, {"type": "string"}},
+			"x-kubernetes-int-or-string": true,
+		}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int32, reflect.Uint32:
+		return schema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return schema{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return schema{"type": "string", "format": "byte"}
+		}
+		return schema{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		props := schema{}
+		g.properties(t, props)
+		return schema{"type": "object", "properties": props}
+	}
+	panic(fmt.Sprintf("unsupported type %s", t))
+}
+
+// properties adds the schemas of the serialized fields of a struct type.
+// Inlined structs are flattened.
+func (g *generator) properties(t reflect.Type, props schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		if strings.Contains(tag, ",inline") || (f.Anonymous && tag == "") {
+			g.properties(f.Type, props)
+			continue
+		}
+		s := g.schema(f.Type)
+		if doc, ok := g.docs[t.PkgPath()+"."+t.Name()+"."+f.Name]; ok {
+			s["description"] = doc
+		}
+		props[jsonName(f)] = s
+	}
+}
+
+// jsonName returns the serialized name of a struct field.
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if len(name) == 0 {
+		return f.Name
+	}
+	return name
+}
+
+// parseDocs adds the doc comments of the struct fields of an API package of
+// the repository. The generator is run from the repository root.
+func (g *generator) parseDocs(pkgPath string) error {
+	dir := strings.TrimPrefix(pkgPath, modulePath)
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				ts, ok := n.(*ast.TypeSpec)
+				if !ok {
+					return true
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					return false
+				}
+				for _, f := range st.Fields.List {
+					if f.Doc == nil {
+						continue
+					}
+					text := strings.Join(strings.Fields(f.Doc.Text()), " ")
+					for _, name := range f.Names {
+						g.docs[pkgPath+"."+ts.Name.Name+"."+name.Name] = text
+					}
+				}
+				return false
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+#!/usr/bin/env bash
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+go run ./tmp/codegen/crd > deploy/crd.yaml
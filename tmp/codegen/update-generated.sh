@@ -15,6 +15,6 @@ docker run --rm \
   "deepcopy" \
   "github.com/jmckind/jira-operator/pkg/generated" \
   "github.com/jmckind/jira-operator/pkg/apis" \
-  "jira:v1alpha1" \
+  "jira:v1alpha1,v1beta1 monitoring:v1 route:v1 snapshot:v1alpha1" \
   --go-header-file "./tmp/codegen/boilerplate.go.txt" \
   $@